@@ -83,11 +83,13 @@ func (d *Display) allocImage(ai *Image, r Rectangle, pix Pix, repl bool, val uin
 	img.Clipr = clipr
 	img.Screen = nil
 	img.next = nil
+	d.noteAlloc(img)
 
 	return img, nil
 }
 
 // freeimage1 is the internal free that doesn't free the Go struct.
+// Called with d.mu held.
 func (i *Image) freeimage1() error {
 	if i == nil || i.Display == nil {
 		return nil
@@ -100,6 +102,7 @@ func (i *Image) freeimage1() error {
 	}
 	a[0] = 'f'
 	bplong(a[1:], uint32(i.id))
+	d.noteFree(i.id)
 
 	// Remove from screen windows list if needed
 	if i.Screen != nil {
@@ -221,6 +224,7 @@ func (d *Display) allocWindow(win *Image, s *Screen, r Rectangle, ref int, val u
 	i.Repl = false
 	i.R = r
 	i.Clipr = r
+	d.noteAlloc(i)
 
 	// Add to windows list
 	i.next = d.Windows
@@ -361,8 +365,14 @@ func gshort(b []byte) uint16 {
 }
 
 // AllocImageMix allocates a 1x1 replicated image blending two colors.
-// Used for creating halftone patterns.
+// Used for creating halftone patterns. With the BlendLinearLight blend mode
+// the mix is computed in linear light by the client; otherwise devdraw
+// composites color1 onto color3 through a ~25% mask.
 func (d *Display) AllocImageMix(color1, color3 uint32) (*Image, error) {
+	if d.Blend == BlendLinearLight {
+		return d.AllocImage(Rect(0, 0, 1, 1), d.ScreenImage.Pix, true, d.MixColor(color1, color3, 0x3F))
+	}
+
 	// For high bit depth, use alpha blending with ~25% mask
 	t, err := d.AllocImage(Rect(0, 0, 1, 1), d.ScreenImage.Pix, true, color1)
 	if err != nil {
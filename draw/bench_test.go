@@ -0,0 +1,136 @@
+package draw
+
+import (
+	"os"
+	"testing"
+)
+
+// benchDisplay returns a display whose protocol messages are written
+// to /dev/null, with a 1024x768 screen and the built-in default font.
+func benchDisplay(b *testing.B) *Display {
+	fd, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { fd.Close() })
+	d := &Display{datafd: fd, bufsize: drawBufSize}
+	d.buf = make([]byte, d.bufsize+5)
+	d.Image = &Image{Display: d, Pix: RGB24, Depth: 24, R: Rect(0, 0, 1024, 768), Clipr: Rect(0, 0, 1024, 768)}
+	d.ScreenImage = d.Image
+	if d.White, err = d.AllocImage(Rect(0, 0, 1, 1), GREY1, true, DWhite); err != nil {
+		b.Fatal(err)
+	}
+	if d.Black, err = d.AllocImage(Rect(0, 0, 1, 1), GREY1, true, DBlack); err != nil {
+		b.Fatal(err)
+	}
+	d.Opaque = d.White
+	if err := d.builddefont(); err != nil {
+		b.Fatal(err)
+	}
+	return d
+}
+
+const benchText = "The quick brown fox jumps over the lazy dog; 0123456789."
+
+func BenchmarkString(b *testing.B) {
+	d := benchDisplay(b)
+	d.Image.String(ZP, d.Black, ZP, d.DefaultFont, benchText) // warm the cache
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Image.String(Pt(10, 10), d.Black, ZP, d.DefaultFont, benchText)
+	}
+}
+
+func BenchmarkStringBg(b *testing.B) {
+	d := benchDisplay(b)
+	d.Image.StringBg(ZP, d.Black, ZP, d.DefaultFont, benchText, d.White, ZP)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Image.StringBg(Pt(10, 10), d.Black, ZP, d.DefaultFont, benchText, d.White, ZP)
+	}
+}
+
+func BenchmarkCachechars(b *testing.B) {
+	d := benchDisplay(b)
+	f := d.DefaultFont
+	cbuf := make([]uint16, maxCacheChars)
+	s := benchText
+	f.cachechars(&s, nil, cbuf, maxCacheChars) // load the glyphs
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := benchText
+		f.cachechars(&s, nil, cbuf, maxCacheChars)
+	}
+}
+
+func BenchmarkStringWidth(b *testing.B) {
+	d := benchDisplay(b)
+	f := d.DefaultFont
+	f.StringWidth(benchText)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.StringWidth(benchText)
+	}
+}
+
+func BenchmarkDraw(b *testing.B) {
+	d := benchDisplay(b)
+	r := Rect(10, 10, 110, 30)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Image.Draw(r, d.White, ZP)
+	}
+}
+
+func BenchmarkDrawOp(b *testing.B) {
+	d := benchDisplay(b)
+	r := Rect(10, 10, 110, 30)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Image.DrawOp(r, d.White, nil, ZP, S)
+	}
+}
+
+func BenchmarkLine(b *testing.B) {
+	d := benchDisplay(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Image.Line(Pt(10, 10), Pt(500, 300), Endsquare, Endarrow, 1, d.Black, ZP)
+	}
+}
+
+func BenchmarkPoly(b *testing.B) {
+	d := benchDisplay(b)
+	p := make([]Point, 64)
+	for i := range p {
+		p[i] = Pt(10+i*7, 100+(i%5)*13)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Image.Poly(p, Enddisc, Enddisc, 0, d.Black, ZP)
+	}
+}
+
+// BenchmarkFlush measures a frame of typical size: a few hundred draw
+// messages batched into the buffer and flushed.
+func BenchmarkFlush(b *testing.B) {
+	d := benchDisplay(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for y := 0; y < 300; y++ {
+			d.Image.Draw(Rect(0, y, 100, y+1), d.White, ZP)
+		}
+		if err := d.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package draw
+
+import "math"
+
+// BlendMode selects how the client blends colors when it computes
+// them itself, as AllocImageMix and MixColor do.
+type BlendMode int
+
+const (
+	// BlendRaw blends the stored 8-bit channel values directly, as
+	// devdraw does, although they are gamma-encoded. It is the default.
+	BlendRaw BlendMode = iota
+	// BlendLinearLight treats channel values as sRGB-encoded, converts
+	// them to linear light to blend them and back afterwards, so that
+	// mixes of light and dark colors do not come out too dark.
+	BlendLinearLight
+)
+
+// Lookup tables between 8-bit sRGB channel values and 16-bit linear
+// light. lin2srgb is indexed by the linear value shifted right 4 bits.
+var (
+	srgb2lin [256]uint16
+	lin2srgb [4096]uint8
+)
+
+func init() {
+	for i := range srgb2lin {
+		c := float64(i) / 255
+		if c <= 0.04045 {
+			c /= 12.92
+		} else {
+			c = math.Pow((c+0.055)/1.055, 2.4)
+		}
+		srgb2lin[i] = uint16(c*65535 + 0.5)
+	}
+	for i := range lin2srgb {
+		l := (float64(i) + 0.5) / float64(len(lin2srgb))
+		if l <= 0.0031308 {
+			l *= 12.92
+		} else {
+			l = 1.055*math.Pow(l, 1/2.4) - 0.055
+		}
+		lin2srgb[i] = uint8(l*255 + 0.5)
+	}
+}
+
+// MixColor returns the blend of two RGBA colors, giving c1 the weight
+// t/255 and c2 the rest, using the display's blend mode. The colors
+// are premultiplied, as everywhere in draw.
+func (d *Display) MixColor(c1, c2 uint32, t uint8) uint32 {
+	if d != nil && d.Blend == BlendLinearLight {
+		return mixLinearLight(c1, c2, t)
+	}
+	return mixRaw(c1, c2, t)
+}
+
+// mixRaw blends each channel of c1 and c2 directly.
+func mixRaw(c1, c2 uint32, t uint8) uint32 {
+	w1, w2 := uint32(t), 255-uint32(t)
+	var v uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		a := (c1 >> shift) & 0xFF
+		b := (c2 >> shift) & 0xFF
+		v |= ((a*w1 + b*w2 + 127) / 255) << shift
+	}
+	return v
+}
+
+// mixLinearLight blends c1 and c2 in linear light. The color channels are
+// unpremultiplied before conversion, weighted by alpha while mixing,
+// and premultiplied by the mixed alpha afterwards.
+func mixLinearLight(c1, c2 uint32, t uint8) uint32 {
+	a1, a2 := c1&0xFF, c2&0xFF
+	w1 := uint64(t) * uint64(a1)
+	w2 := uint64(255-uint32(t)) * uint64(a2)
+	alpha := (uint32(t)*a1 + (255-uint32(t))*a2 + 127) / 255
+	if w1+w2 == 0 {
+		return 0
+	}
+	v := alpha
+	for shift := uint(8); shift < 32; shift += 8 {
+		l1 := uint64(srgb2lin[unpremul((c1>>shift)&0xFF, a1)])
+		l2 := uint64(srgb2lin[unpremul((c2>>shift)&0xFF, a2)])
+		l := (l1*w1 + l2*w2) / (w1 + w2)
+		c := uint32(lin2srgb[l>>4])
+		v |= (c * alpha / 255) << shift
+	}
+	return v
+}
+
+// unpremul undoes the premultiplication of channel value c by alpha a.
+func unpremul(c, a uint32) uint32 {
+	if a == 0 {
+		return 0
+	}
+	if c >= a {
+		return 255
+	}
+	return (c*255 + a/2) / a
+}
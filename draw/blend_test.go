@@ -0,0 +1,61 @@
+package draw
+
+import "testing"
+
+func TestSRGBTables(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		if got := lin2srgb[srgb2lin[i]>>4]; int(got) != i {
+			t.Errorf("sRGB %d round trips to %d", i, got)
+		}
+	}
+	if srgb2lin[0] != 0 || srgb2lin[255] != 0xFFFF {
+		t.Errorf("srgb2lin ends = %d, %d; want 0, 65535", srgb2lin[0], srgb2lin[255])
+	}
+}
+
+func TestMixColor(t *testing.T) {
+	lin := &Display{}
+	srgb := &Display{Blend: BlendLinearLight}
+	tests := []struct {
+		d      *Display
+		c1, c2 uint32
+		t      uint8
+		want   uint32
+	}{
+		{lin, DWhite, DBlack, 128, 0x808080FF},
+		{srgb, DWhite, DBlack, 128, 0xBCBCBCFF}, // half linear light
+		{lin, DRed, DBlue, 255, DRed},
+		{srgb, DRed, DBlue, 255, DRed},
+		{srgb, DRed, DBlue, 0, DBlue},
+		{srgb, DGreen, DGreen, 77, DGreen},
+		{srgb, DTransparent, DTransparent, 128, DTransparent},
+		// Mixing with transparent keeps the hue and halves the alpha.
+		{srgb, DRed, DTransparent, 128, 0x80000080},
+		{nil, DWhite, DBlack, 0, DBlack},
+	}
+	for _, tt := range tests {
+		if got := tt.d.MixColor(tt.c1, tt.c2, tt.t); got != tt.want {
+			t.Errorf("MixColor(%#08x, %#08x, %d) mode %v = %#08x, want %#08x",
+				tt.c1, tt.c2, tt.t, tt.d != nil && tt.d.Blend == BlendLinearLight, got, tt.want)
+		}
+	}
+}
+
+func TestAllocImageMixLinearLight(t *testing.T) {
+	d := bufDisplay()
+	d.Blend = BlendLinearLight
+	d.ScreenImage = &Image{Display: d, Pix: RGB24, Depth: 24}
+	i, err := d.AllocImageMix(DWhite, DBlack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !i.Repl || i.R != Rect(0, 0, 1, 1) {
+		t.Errorf("image = %v repl %v, want 1x1 replicated", i.R, i.Repl)
+	}
+	if st := d.ImageStats(); st.Allocs != 1 {
+		t.Errorf("allocated %d images, want 1", st.Allocs)
+	}
+	if got, want := glong(d.buf[47:]), d.MixColor(DWhite, DBlack, 0x3F); got != want {
+		t.Errorf("fill color = %#08x, want %#08x", got, want)
+	}
+}
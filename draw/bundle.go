@@ -0,0 +1,218 @@
+package draw
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Bundle is a set of resources (fonts, subfonts, images, cursors and
+// colors) read from a single archive, so that a program can carry
+// them in its binary with go:embed instead of depending on /lib/font.
+//
+// The archive holds a file named "manifest" at its root. Each line of
+// the manifest names one resource:
+//
+//	kind name value
+//
+// where kind is font, subfont, image, cursor or color. For colors the
+// value is an RGBA number such as 0xFFFFEAFF; for the other kinds it is
+// the path of a file in the archive. Blank lines and lines starting
+// with # are ignored.
+//
+// Font files are in the usual font description format and name their
+// subfonts relative to themselves, as on disk. Images and subfonts are
+// in the image file format. A cursor file holds 72 bytes in the layout
+// written to /dev/cursor: offset.x[4] offset.y[4] clr[32] set[32].
+type Bundle struct {
+	fsys    fs.FS
+	entries map[string]string // kind+" "+name -> value
+}
+
+// Resource kinds in a bundle manifest.
+var bundleKinds = map[string]bool{
+	"font":    true,
+	"subfont": true,
+	"image":   true,
+	"cursor":  true,
+	"color":   true,
+}
+
+// OpenBundle opens a bundle held in a zip archive, such as a file
+// embedded with go:embed into a []byte.
+func OpenBundle(data []byte) (*Bundle, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("openbundle: %v", err)
+	}
+	return OpenBundleFS(zr)
+}
+
+// OpenBundleFS opens a bundle laid out in a file system, such as an
+// embed.FS directory tree.
+func OpenBundleFS(fsys fs.FS) (*Bundle, error) {
+	f, err := fsys.Open("manifest")
+	if err != nil {
+		return nil, fmt.Errorf("openbundle: %v", err)
+	}
+	defer f.Close()
+
+	b := &Bundle{fsys: fsys, entries: make(map[string]string)}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("openbundle: manifest line %d: want kind name value", n)
+		}
+		if !bundleKinds[fields[0]] {
+			return nil, fmt.Errorf("openbundle: manifest line %d: unknown kind %q", n, fields[0])
+		}
+		b.entries[fields[0]+" "+fields[1]] = fields[2]
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("openbundle: %v", err)
+	}
+	return b, nil
+}
+
+// FS returns the file system holding the bundle's files.
+func (b *Bundle) FS() fs.FS {
+	return b.fsys
+}
+
+// Names returns the sorted names of the resources of the given kind.
+func (b *Bundle) Names(kind string) []string {
+	var names []string
+	for k := range b.entries {
+		if strings.HasPrefix(k, kind+" ") {
+			names = append(names, k[len(kind)+1:])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup returns the manifest value of the named resource.
+func (b *Bundle) lookup(kind, name string) (string, error) {
+	v, ok := b.entries[kind+" "+name]
+	if !ok {
+		return "", fmt.Errorf("bundle: no %s %q", kind, name)
+	}
+	return v, nil
+}
+
+// open opens the file of the named resource.
+func (b *Bundle) open(kind, name string) (fs.File, string, error) {
+	file, err := b.lookup(kind, name)
+	if err != nil {
+		return nil, "", err
+	}
+	f, err := b.fsys.Open(bundlePath(file))
+	if err != nil {
+		return nil, "", fmt.Errorf("bundle: %s %q: %v", kind, name, err)
+	}
+	return f, file, nil
+}
+
+// Font opens the named font on display d. Its subfonts are read from
+// the bundle, falling back to the file system for any the bundle
+// does not hold, such as absolute names of system subfonts.
+func (b *Bundle) Font(d *Display, name string) (*Font, error) {
+	f, file, err := b.open("font", name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("bundle: font %q: %v", name, err)
+	}
+	fnt, err := d.BuildFont(data, file)
+	if err != nil {
+		return nil, fmt.Errorf("bundle: font %q: %v", name, err)
+	}
+	fnt.fs = b.fsys
+	return fnt, nil
+}
+
+// Subfont opens the named subfont on display d.
+func (b *Bundle) Subfont(d *Display, name string) (*Subfont, error) {
+	f, file, err := b.open("subfont", name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSubfont(d, file, f)
+}
+
+// Image reads the named image onto display d.
+func (b *Bundle) Image(d *Display, name string) (*Image, error) {
+	f, _, err := b.open("image", name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return d.ReadImageReader(f)
+}
+
+// Cursor returns the named cursor.
+func (b *Bundle) Cursor(name string) (*Cursor, error) {
+	f, _, err := b.open("cursor", name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	buf := make([]byte, 4+4+2*16+2*16)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return nil, fmt.Errorf("bundle: cursor %q: %v", name, err)
+	}
+	c := &Cursor{Offset: Pt(int(int32(glong(buf[0:]))), int(int32(glong(buf[4:]))))}
+	copy(c.Clr[:], buf[8:])
+	copy(c.Set[:], buf[8+2*16:])
+	return c, nil
+}
+
+// Color returns the named color.
+func (b *Bundle) Color(name string) (uint32, error) {
+	v, err := b.lookup("color", name)
+	if err != nil {
+		return 0, err
+	}
+	c, err := strconv.ParseUint(v, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("bundle: color %q: bad value %q", name, v)
+	}
+	return uint32(c), nil
+}
+
+// bundlePath converts a file name as written in a manifest or font
+// file to a path in the bundle's file system.
+func bundlePath(name string) string {
+	return path.Clean(strings.TrimPrefix(name, "/"))
+}
+
+// openSubfontFS opens a subfont named by a font that was loaded from
+// fsys, consulting the subfont cache first.
+func (d *Display) openSubfontFS(fsys fs.FS, name string) *Subfont {
+	if sf := LookupSubfont(d, name); sf != nil {
+		return sf
+	}
+	f, err := fsys.Open(bundlePath(name))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	sf, _ := ReadSubfont(d, name, f)
+	return sf
+}
@@ -0,0 +1,140 @@
+package draw
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// testSubfontFile returns a two-character GREY1 subfont 8 pixels high
+// in the subfont file format.
+func testSubfontFile() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%11s %11d %11d %11d %11d ", "k1", 0, 0, 16, 8)
+	b.Write(make([]byte, 2*8))
+	WriteSubfont(&b, &Subfont{N: 2, Height: 8, Ascent: 6, Info: []Fontchar{
+		{X: 0, Bottom: 8, Width: 8},
+		{X: 8, Bottom: 8, Width: 8},
+		{X: 16},
+	}})
+	return b.Bytes()
+}
+
+// testBundle returns a zip archive holding a bundle of each kind of
+// resource.
+func testBundle(t *testing.T, manifest string) []byte {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	cursor := make([]byte, 72)
+	bplong(cursor[0:], uint32(0xFFFFFFF9)) // -7
+	bplong(cursor[4:], 3)
+	cursor[8] = 0xAA
+	cursor[40] = 0x55
+	files := map[string][]byte{
+		"manifest":         []byte(manifest),
+		"font/test.font":   []byte("8 6\n0x41 0x42 test.0\n"),
+		"font/test.0":      testSubfontFile(),
+		"cursor/crosshair": cursor,
+		"img/dot":          []byte(fmt.Sprintf("%11s %11d %11d %11d %11d \xff", "k8", 0, 0, 1, 1)),
+	}
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+const testManifest = `# test bundle
+font	body	font/test.font
+subfont	test	/font/test.0
+image	dot	img/dot
+cursor	crosshair	cursor/crosshair
+color	body	0xFFFFEAFF
+`
+
+func TestOpenBundle(t *testing.T) {
+	b, err := OpenBundle(testBundle(t, testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := bufDisplay()
+
+	if c, err := b.Color("body"); err != nil || c != DAcmeYellow {
+		t.Errorf("Color(body) = %#08x, %v; want %#08x", c, err, uint32(DAcmeYellow))
+	}
+	if _, err := b.Color("tag"); err == nil {
+		t.Error("Color(tag) succeeded for a missing color")
+	}
+
+	c, err := b.Cursor("crosshair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Offset != Pt(-7, 3) || c.Clr[0] != 0xAA || c.Set[0] != 0x55 {
+		t.Errorf("cursor = %+v", c)
+	}
+
+	i, err := b.Image(d, "dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.R != Rect(0, 0, 1, 1) || i.Pix != GREY8 {
+		t.Errorf("image = %v %v, want 1x1 k8", i.R, i.Pix)
+	}
+
+	sf, err := b.Subfont(d, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.N != 2 || sf.Height != 8 || sf.Ascent != 6 {
+		t.Errorf("subfont = n %d height %d ascent %d, want 2 8 6", sf.N, sf.Height, sf.Ascent)
+	}
+
+	f, err := b.Font(d, "body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Height != 8 || f.Ascent != 6 || f.fs == nil {
+		t.Fatalf("font = height %d ascent %d fs %v", f.Height, f.Ascent, f.fs)
+	}
+	// The font's subfont comes from the bundle, not the disk.
+	sf = cf2subfont(f.sub[0], f)
+	if sf == nil || sf.N != 2 {
+		t.Errorf("cf2subfont = %+v, want the bundled subfont", sf)
+	}
+
+	if names := b.Names("color"); len(names) != 1 || names[0] != "body" {
+		t.Errorf("Names(color) = %q", names)
+	}
+}
+
+func TestOpenBundleErrors(t *testing.T) {
+	if _, err := OpenBundle([]byte("not a zip")); err == nil {
+		t.Error("OpenBundle accepted a non-zip")
+	}
+	for _, m := range []string{
+		"font body\n",
+		"sound beep beep.wav\n",
+	} {
+		if _, err := OpenBundle(testBundle(t, m)); err == nil {
+			t.Errorf("OpenBundle accepted manifest %q", m)
+		}
+	}
+	b, err := OpenBundle(testBundle(t, "font missing font/none.font\ncolor bad purple\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Font(bufDisplay(), "missing"); err == nil {
+		t.Error("Font succeeded for a missing file")
+	}
+	if _, err := b.Color("bad"); err == nil {
+		t.Error("Color succeeded for a bad value")
+	}
+}
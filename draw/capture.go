@@ -0,0 +1,150 @@
+package draw
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+)
+
+// CaptureRect reads the pixels in r from the screen image (or the
+// display image if there is no window) and returns them as an RGBA
+// image whose bounds match r. The rectangle is clipped to the image.
+func (d *Display) CaptureRect(r Rectangle) (*image.RGBA, error) {
+	src := d.ScreenImage
+	if src == nil {
+		src = d.Image
+	}
+	if src == nil {
+		return nil, fmt.Errorf("capturerect: no screen image")
+	}
+	return src.Capture(r)
+}
+
+// WritePNG captures r from the screen and writes it to w in PNG format.
+func (d *Display) WritePNG(w io.Writer, r Rectangle) error {
+	m, err := d.CaptureRect(r)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, m)
+}
+
+// Capture reads the pixels in r from the image and converts them
+// from the image's channel format to RGBA.
+func (i *Image) Capture(r Rectangle) (*image.RGBA, error) {
+	if i == nil || i.Display == nil {
+		return nil, fmt.Errorf("capture: nil image or display")
+	}
+	r, ok := r.Clip(i.R)
+	if !ok {
+		return nil, fmt.Errorf("capture: rectangle outside image")
+	}
+	data, err := i.ReadPixels(r)
+	if err != nil {
+		return nil, err
+	}
+	return unpackRGBA(i.Pix, r, data)
+}
+
+// unpackRGBA converts pixel data laid out as Unload returns it into
+// an RGBA image with bounds r. Plan 9 pixels are premultiplied, as
+// are image.RGBA pixels, so color values are copied unchanged.
+func unpackRGBA(pix Pix, r Rectangle, data []byte) (*image.RGBA, error) {
+	depth := chantodepth(pix)
+	if depth == 0 {
+		return nil, fmt.Errorf("unpack: bad channel descriptor %#x", uint32(pix))
+	}
+	bpl := bytesPerLine(r, depth)
+	if len(data) < bpl*r.Dy() {
+		return nil, fmt.Errorf("unpack: short data: need %d, got %d", bpl*r.Dy(), len(data))
+	}
+
+	m := image.NewRGBA(image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y))
+	for y := 0; y < r.Dy(); y++ {
+		row := data[y*bpl : (y+1)*bpl]
+		o := m.PixOffset(r.Min.X, r.Min.Y+y)
+		for x := 0; x < r.Dx(); x++ {
+			v := pixelValue(row, r.Min.X, r.Min.X+x, depth)
+			cr, cg, cb, ca := pixToRGBA(pix, v)
+			m.Pix[o+0] = cr
+			m.Pix[o+1] = cg
+			m.Pix[o+2] = cb
+			m.Pix[o+3] = ca
+			o += 4
+		}
+	}
+	return m, nil
+}
+
+// pixelValue extracts the raw value of the pixel at column x from a
+// scan line whose first byte holds column minx. Sub-byte pixels are
+// packed most significant bits first; wider pixels are little-endian.
+func pixelValue(row []byte, minx, x, depth int) uint32 {
+	if depth < 8 {
+		bit := x*depth - (minx*depth)&^7
+		b := row[bit>>3]
+		shift := 8 - depth - bit&7
+		return uint32(b>>uint(shift)) & (1<<uint(depth) - 1)
+	}
+	nb := depth / 8
+	off := (x - minx) * nb
+	var v uint32
+	for k := nb - 1; k >= 0; k-- {
+		v = v<<8 | uint32(row[off+k])
+	}
+	return v
+}
+
+// pixToRGBA splits a raw pixel value into 8-bit red, green, blue and
+// alpha according to the channel descriptor. The last channel of the
+// descriptor occupies the least significant bits.
+func pixToRGBA(pix Pix, v uint32) (r, g, b, a uint8) {
+	a = 0xFF
+	shift := uint(0)
+	for c := pix; c != 0; c >>= 8 {
+		t := int(c>>4) & 0xF
+		n := uint(c & 0xF)
+		cv := (v >> shift) & (1<<n - 1)
+		shift += n
+		switch t {
+		case CRed:
+			r = expandChan(cv, n)
+		case CGreen:
+			g = expandChan(cv, n)
+		case CBlue:
+			b = expandChan(cv, n)
+		case CGrey:
+			r = expandChan(cv, n)
+			g, b = r, r
+		case CAlpha:
+			a = expandChan(cv, n)
+		case CMap:
+			rgb := Cmap2rgb(int(cv))
+			r = uint8(rgb >> 16)
+			g = uint8(rgb >> 8)
+			b = uint8(rgb)
+		}
+	}
+	return
+}
+
+// expandChan scales an n-bit channel value to 8 bits by bit
+// replication, so that all ones maps to 0xFF.
+func expandChan(v uint32, n uint) uint8 {
+	if n == 0 {
+		return 0
+	}
+	if n >= 8 {
+		return uint8(v >> (n - 8))
+	}
+	var x uint32
+	for s := int(8 - n); s > -int(n); s -= int(n) {
+		if s >= 0 {
+			x |= v << uint(s)
+		} else {
+			x |= v >> uint(-s)
+		}
+	}
+	return uint8(x)
+}
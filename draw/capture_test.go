@@ -0,0 +1,164 @@
+package draw
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestExpandChan verifies channel widening by bit replication.
+func TestExpandChan(t *testing.T) {
+	tests := []struct {
+		v    uint32
+		n    uint
+		want uint8
+	}{
+		{0, 1, 0x00},
+		{1, 1, 0xFF},
+		{1, 2, 0x55},
+		{2, 2, 0xAA},
+		{3, 2, 0xFF},
+		{0xA, 4, 0xAA},
+		{0x1F, 5, 0xFF},
+		{0x10, 5, 0x84},
+		{0x3F, 6, 0xFF},
+		{0x80, 8, 0x80},
+	}
+	for _, tt := range tests {
+		if got := expandChan(tt.v, tt.n); got != tt.want {
+			t.Errorf("expandChan(%#x, %d) = %#x, want %#x", tt.v, tt.n, got, tt.want)
+		}
+	}
+}
+
+// TestPixToRGBA verifies channel extraction for common formats.
+func TestPixToRGBA(t *testing.T) {
+	tests := []struct {
+		name       string
+		pix        Pix
+		v          uint32
+		r, g, b, a uint8
+	}{
+		{"grey1 white", GREY1, 1, 0xFF, 0xFF, 0xFF, 0xFF},
+		{"grey8", GREY8, 0x40, 0x40, 0x40, 0x40, 0xFF},
+		{"rgb24", RGB24, 0x112233, 0x11, 0x22, 0x33, 0xFF},
+		{"xrgb32", XRGB32, 0xEE112233, 0x11, 0x22, 0x33, 0xFF},
+		{"rgba32", RGBA32, 0x11223380, 0x11, 0x22, 0x33, 0x80},
+		{"argb32", ARGB32, 0x80112233, 0x11, 0x22, 0x33, 0x80},
+		{"rgb16 red", RGB16, 0xF800, 0xFF, 0x00, 0x00, 0xFF},
+		{"cmap8 white", CMAP8, 255, 0xFF, 0xFF, 0xFF, 0xFF},
+		{"cmap8 black", CMAP8, 0, 0x00, 0x00, 0x00, 0xFF},
+	}
+	for _, tt := range tests {
+		r, g, b, a := pixToRGBA(tt.pix, tt.v)
+		if r != tt.r || g != tt.g || b != tt.b || a != tt.a {
+			t.Errorf("%s: got (%#x,%#x,%#x,%#x), want (%#x,%#x,%#x,%#x)",
+				tt.name, r, g, b, a, tt.r, tt.g, tt.b, tt.a)
+		}
+	}
+}
+
+// TestUnpackRGBA24 verifies byte order of packed 24-bit data.
+func TestUnpackRGBA24(t *testing.T) {
+	r := Rect(5, 7, 7, 8)
+	// Two RGB24 pixels, little-endian: b, g, r.
+	data := []byte{0x33, 0x22, 0x11, 0x66, 0x55, 0x44}
+	m, err := unpackRGBA(RGB24, r, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := m.Bounds(); b.Min.X != 5 || b.Min.Y != 7 || b.Dx() != 2 || b.Dy() != 1 {
+		t.Fatalf("bounds = %v, want (5,7)-(7,8)", b)
+	}
+	c := m.RGBAAt(5, 7)
+	if c.R != 0x11 || c.G != 0x22 || c.B != 0x33 || c.A != 0xFF {
+		t.Errorf("pixel 0 = %v, want {0x11 0x22 0x33 0xff}", c)
+	}
+	c = m.RGBAAt(6, 7)
+	if c.R != 0x44 || c.G != 0x55 || c.B != 0x66 {
+		t.Errorf("pixel 1 = %v, want {0x44 0x55 0x66 0xff}", c)
+	}
+}
+
+// TestUnpackRGBAGrey1Offset verifies sub-byte pixels when the
+// rectangle does not start on a byte boundary.
+func TestUnpackRGBAGrey1Offset(t *testing.T) {
+	// Columns 3..13 at depth 1: the row starts with the byte
+	// holding columns 0..7, so column 3 is bit 3 from the top.
+	r := Rect(3, 0, 13, 1)
+	data := []byte{0x10, 0x40} // column 3 set, column 9 set
+	m, err := unpackRGBA(GREY1, r, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := 3; x < 13; x++ {
+		want := uint8(0)
+		if x == 3 || x == 9 {
+			want = 0xFF
+		}
+		if got := m.RGBAAt(x, 0).R; got != want {
+			t.Errorf("column %d = %#x, want %#x", x, got, want)
+		}
+	}
+}
+
+// TestUnpackRGBAErrors verifies bad input is rejected.
+func TestUnpackRGBAErrors(t *testing.T) {
+	if _, err := unpackRGBA(0, Rect(0, 0, 1, 1), []byte{0}); err == nil {
+		t.Error("unpackRGBA with bad pix should fail")
+	}
+	if _, err := unpackRGBA(XRGB32, Rect(0, 0, 2, 2), make([]byte, 4)); err == nil {
+		t.Error("unpackRGBA with short data should fail")
+	}
+}
+
+// TestCaptureNil verifies nil image safety.
+func TestCaptureNil(t *testing.T) {
+	var img *Image
+	if _, err := img.Capture(Rect(0, 0, 1, 1)); err == nil {
+		t.Error("Capture on nil image should fail")
+	}
+	d := &Display{}
+	if _, err := d.CaptureRect(Rect(0, 0, 1, 1)); err == nil {
+		t.Error("CaptureRect without a screen image should fail")
+	}
+}
+
+// TestReadPixels verifies reading lines wider than one read message,
+// including from columns that do not start on a byte.
+func TestReadPixels(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	for _, tt := range []struct {
+		r, s Rectangle // image and rectangle read
+		pix  Pix
+	}{
+		{Rect(0, 0, 40, 30), Rect(0, 0, 40, 30), RGB24},
+		{Rect(-7, 2, 2600, 5), Rect(-7, 2, 2600, 5), RGBA32},
+		{Rect(3, 0, 3001, 2), Rect(4, 0, 3001, 2), RGB24},
+		{Rect(0, 1, 70000, 3), Rect(5, 1, 69999, 3), GREY1},
+		{Rect(0, 0, 40000, 2), Rect(1, 0, 40000, 2), GREY2},
+	} {
+		i, data := loadTestImage(t, d, tt.r, tt.pix)
+		got, err := i.ReadPixels(tt.s)
+		if err != nil {
+			t.Fatalf("%v %s: %v", tt.s, chantostr(tt.pix), err)
+		}
+		bpl := bytesPerLine(tt.r, i.Depth)
+		sbpl := bytesPerLine(tt.s, i.Depth)
+		off := (tt.s.Min.X*i.Depth)>>3 - (tt.r.Min.X*i.Depth)>>3
+		var want []byte
+		for y := 0; y < tt.s.Dy(); y++ {
+			want = append(want, data[(tt.s.Min.Y-tt.r.Min.Y+y)*bpl+off:][:sbpl]...)
+		}
+		// Compare pixels, ignoring bits of columns outside tt.s.
+		gm, _ := unpackRGBA(tt.pix, tt.s, got)
+		wm, _ := unpackRGBA(tt.pix, tt.s, want)
+		if len(got) != len(want) || !bytes.Equal(gm.Pix, wm.Pix) {
+			t.Errorf("%v %s: pixels differ", tt.s, chantostr(tt.pix))
+		}
+		i.Free()
+	}
+	i, _ := d.AllocImage(Rect(0, 0, 10, 10), RGB24, false, DWhite)
+	if _, err := i.ReadPixels(Rect(5, 5, 11, 6)); err == nil {
+		t.Error("ReadPixels outside the image succeeded")
+	}
+}
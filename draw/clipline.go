@@ -0,0 +1,140 @@
+package draw
+
+// Default arrow head dimensions used by Endarrow, from draw.h.
+const (
+	Arrow1 = 8  // length of the arrow along the line, at the tip
+	Arrow2 = 10 // length along the line to the back of the barbs
+	Arrow3 = 3  // width of the barbs beyond the line
+)
+
+// Arrow returns an end style for an arrow head with the given
+// dimensions, as the ARROW macro in draw.h.
+func Arrow(a, b, c int) int {
+	return Endarrow | a<<5 | b<<14 | c<<23
+}
+
+// arrowsize returns the dimensions of the arrow head of an Endarrow
+// end style: the defaults, or those given to Arrow.
+func arrowsize(end int) (a, b, c int) {
+	if end>>5 == 0 {
+		return Arrow1, Arrow2, Arrow3
+	}
+	return (end >> 5) & 0x1FF, (end >> 14) & 0x1FF, (end >> 23) & 0x1FF
+}
+
+// endmargin returns how far beyond its endpoint, in any direction,
+// an end of the given style can mark pixels on a line of the radius.
+func endmargin(end, radius int) int {
+	m := 2*radius + 1 // a square end's corners, with rounding
+	if end&Endmask == Endarrow {
+		a, b, c := arrowsize(end)
+		if a < b {
+			a = b
+		}
+		if a < radius+c {
+			a = radius + c
+		}
+		m += a
+	}
+	return m
+}
+
+// visclip returns the part of dst that drawing can change, and whether
+// client-side rejection applies at all; it does not for a replicated
+// dst. The rectangle is empty if nothing can be drawn.
+func visclip(dst *Image) (Rectangle, bool) {
+	if dst.Repl {
+		return ZR, false
+	}
+	r, ok := dst.Clipr.Clip(dst.R)
+	if !ok {
+		return ZR, true
+	}
+	return r, true
+}
+
+// segvisible reports whether the segment p0-p1, thickened by margin in
+// every direction, can touch r. It is a Liang-Barsky test against r
+// grown by margin, so it never rejects a segment that could mark a
+// pixel inside r.
+func segvisible(p0, p1 Point, margin int, r Rectangle) bool {
+	r = r.Inset(-margin)
+	// Trivial acceptance and rejection first, as Cohen-Sutherland does.
+	if p0.In(r) || p1.In(r) {
+		return true
+	}
+	if p0.X < r.Min.X && p1.X < r.Min.X || p0.X >= r.Max.X && p1.X >= r.Max.X ||
+		p0.Y < r.Min.Y && p1.Y < r.Min.Y || p0.Y >= r.Max.Y && p1.Y >= r.Max.Y {
+		return false
+	}
+	// The segment straddles the rectangle's extent; clip its
+	// parameter interval against each edge. Rounding here cannot
+	// matter, since the margin is generous.
+	dx := float64(p1.X - p0.X)
+	dy := float64(p1.Y - p0.Y)
+	t0, t1 := 0.0, 1.0
+	edges := [4][2]float64{
+		{-dx, float64(p0.X - r.Min.X)},
+		{dx, float64(r.Max.X - 1 - p0.X)},
+		{-dy, float64(p0.Y - r.Min.Y)},
+		{dy, float64(r.Max.Y - 1 - p0.Y)},
+	}
+	for _, e := range edges {
+		p, q := e[0], e[1]
+		if p == 0 {
+			if q < 0 {
+				return false
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			if t > t1 {
+				return false
+			}
+			if t > t0 {
+				t0 = t
+			}
+		} else {
+			if t < t0 {
+				return false
+			}
+			if t < t1 {
+				t1 = t
+			}
+		}
+	}
+	return t0 <= t1
+}
+
+// polyruns splits the polyline p into runs of consecutive segments
+// that may be visible in r, dropping the segments that cannot be. Each
+// run is given as the indices of its first and last points.
+func polyruns(p []Point, end0, end1, radius int, r Rectangle) [][2]int {
+	var runs [][2]int
+	start := -1
+	last := len(p) - 1
+	for k := 0; k < last; k++ {
+		m := 2*radius + 1
+		if k == 0 {
+			m = max(m, endmargin(end0, radius))
+		}
+		if k+1 == last {
+			m = max(m, endmargin(end1, radius))
+		}
+		if segvisible(p[k], p[k+1], m, r) {
+			if start < 0 {
+				start = k
+			}
+			continue
+		}
+		if start >= 0 {
+			runs = append(runs, [2]int{start, k})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		runs = append(runs, [2]int{start, last})
+	}
+	return runs
+}
@@ -0,0 +1,143 @@
+package draw
+
+import "testing"
+
+func TestEndmargin(t *testing.T) {
+	tests := []struct {
+		end, radius, want int
+	}{
+		{Endsquare, 0, 1},
+		{Enddisc, 3, 7},
+		{Endarrow, 0, 1 + Arrow2},
+		{Endarrow, 2, 5 + Arrow2},
+		{Arrow(20, 4, 2), 1, 3 + 20},
+		{Arrow(2, 3, 40), 1, 3 + 41},
+	}
+	for _, tt := range tests {
+		if got := endmargin(tt.end, tt.radius); got != tt.want {
+			t.Errorf("endmargin(%#x, %d) = %d, want %d", tt.end, tt.radius, got, tt.want)
+		}
+	}
+}
+
+func TestSegvisible(t *testing.T) {
+	r := Rect(0, 0, 100, 100)
+	tests := []struct {
+		name   string
+		p0, p1 Point
+		margin int
+		want   bool
+	}{
+		{"inside", Pt(10, 10), Pt(20, 20), 1, true},
+		{"one end inside", Pt(50, 50), Pt(500, 50), 1, true},
+		{"crossing", Pt(-50, 50), Pt(150, 50), 1, true},
+		{"diagonal crossing", Pt(-10, 110), Pt(110, -10), 1, true},
+		{"left", Pt(-50, 0), Pt(-20, 100), 1, false},
+		{"below", Pt(0, 150), Pt(100, 120), 1, false},
+		{"within margin", Pt(-5, 0), Pt(-5, 100), 5, true},
+		{"just beyond margin", Pt(-6, 0), Pt(-6, 100), 5, false},
+		// Its bounding box overlaps r but the segment passes the corner.
+		{"past corner", Pt(90, -20), Pt(130, 20), 1, false},
+		{"near corner", Pt(90, -20), Pt(130, 20), 10, true},
+		{"point", Pt(200, 200), Pt(200, 200), 1, false},
+	}
+	for _, tt := range tests {
+		if got := segvisible(tt.p0, tt.p1, tt.margin, r); got != tt.want {
+			t.Errorf("%s: segvisible = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPolyruns(t *testing.T) {
+	r := Rect(0, 0, 100, 100)
+	p := []Point{
+		{-500, 50}, {-400, 50}, // off left
+		{50, 50}, {60, 60}, // visible
+		{500, 60}, {600, 60}, {700, 60}, // off right
+		{50, 90}, // back in
+	}
+	runs := polyruns(p, Enddisc, Enddisc, 0, r)
+	want := [][2]int{{1, 4}, {6, 7}}
+	if len(runs) != len(want) {
+		t.Fatalf("runs = %v, want %v", runs, want)
+	}
+	for i := range want {
+		if runs[i] != want[i] {
+			t.Errorf("runs = %v, want %v", runs, want)
+		}
+	}
+}
+
+// polymsgs decodes the 'p' messages in a display buffer, returning
+// for each its point count, end styles and sp.
+func polymsgs(t *testing.T, b []byte) [][5]int {
+	var msgs [][5]int
+	for len(b) > 0 {
+		if b[0] != 'p' {
+			t.Fatalf("unexpected message %q", b[0])
+		}
+		n := int(gshort(b[5:])) + 1
+		m := [5]int{n, int(glong(b[7:])), int(glong(b[11:])), int(int32(glong(b[23:]))), int(int32(glong(b[27:])))}
+		msgs = append(msgs, m)
+		// Skip the compressed coordinates.
+		k := 31
+		for i := 0; i < 2*n; i++ {
+			if b[k]&0x80 != 0 {
+				k += 3
+			} else {
+				k++
+			}
+		}
+		b = b[k:]
+	}
+	return msgs
+}
+
+func TestPolyClip(t *testing.T) {
+	d := bufDisplay()
+	dst := &Image{Display: d, id: 1, R: Rect(0, 0, 100, 100), Clipr: Rect(0, 0, 100, 100)}
+	src := &Image{Display: d, id: 2}
+
+	// Fully visible: one message, unchanged.
+	dst.Poly([]Point{{10, 10}, {20, 20}, {30, 10}}, Endarrow, Endsquare, 1, src, Pt(5, 5))
+	msgs := polymsgs(t, d.buf[:d.bufp])
+	if len(msgs) != 1 || msgs[0] != [5]int{3, Endarrow, Endsquare, 5, 5} {
+		t.Errorf("visible poly = %v", msgs)
+	}
+
+	// Off screen in the middle: two runs, interior ends discs, sp
+	// following the start of each run.
+	d.bufp = 0
+	p := []Point{{10, 10}, {50, 10}, {500, 10}, {600, 10}, {700, 50}, {50, 50}}
+	dst.Poly(p, Endarrow, Endsquare, 0, src, Pt(1, 2))
+	msgs = polymsgs(t, d.buf[:d.bufp])
+	want := [][5]int{
+		{3, Endarrow, Enddisc, 1, 2},
+		{2, Enddisc, Endsquare, 1 + 690, 2 + 40},
+	}
+	if len(msgs) != 2 || msgs[0] != want[0] || msgs[1] != want[1] {
+		t.Errorf("split poly = %v, want %v", msgs, want)
+	}
+
+	// Entirely off screen: nothing.
+	d.bufp = 0
+	dst.Poly([]Point{{200, 200}, {300, 300}}, Enddisc, Enddisc, 2, src, ZP)
+	dst.Line(Pt(200, 200), Pt(300, 300), Enddisc, Enddisc, 2, src, ZP)
+	if d.bufp != 0 {
+		t.Errorf("off-screen poly and line sent %d bytes", d.bufp)
+	}
+
+	// A line whose arrow head reaches into view is kept.
+	dst.Line(Pt(-200, 50), Pt(-5, 50), Endsquare, Endarrow, 0, src, ZP)
+	if d.bufp == 0 || d.buf[0] != 'L' {
+		t.Error("line with visible arrow head not sent")
+	}
+
+	// Replicated destinations are never clipped.
+	d.bufp = 0
+	dst.Repl = true
+	dst.Line(Pt(200, 200), Pt(300, 300), Enddisc, Enddisc, 0, src, ZP)
+	if d.bufp == 0 {
+		t.Error("line on replicated image not sent")
+	}
+}
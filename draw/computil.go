@@ -129,39 +129,117 @@ func (i *Image) Unload(r Rectangle, data []byte) (int, error) {
 	return ndata, nil
 }
 
-// Cload loads compressed image data.
+// ReadPixels returns the pixels in r, which must lie within the image,
+// in the image's own channel format and laid out as for Load: each
+// line begins with the byte holding column r.Min.X. Unlike Unload it
+// handles lines too wide for a single read message, so it can read
+// the whole of a large screen.
+func (i *Image) ReadPixels(r Rectangle) ([]byte, error) {
+	if i == nil || i.Display == nil {
+		return nil, fmt.Errorf("readpixels: nil image or display")
+	}
+	if !r.In(i.R) {
+		return nil, fmt.Errorf("readpixels: bad rectangle")
+	}
+	bpl := bytesPerLine(r, i.Depth)
+	data := make([]byte, bpl*r.Dy())
+	chunk := i.Display.bufsize - 64
+	if bpl <= chunk {
+		if _, err := i.Unload(r, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	// Read each line in strips whose edges fall on multiples of 8
+	// pixels, and so on byte boundaries at any depth.
+	w := (chunk * 8 / i.Depth) &^ 7
+	tmp := make([]byte, chunk)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		line := data[(y-r.Min.Y)*bpl:]
+		for x0 := r.Min.X; x0 < r.Max.X; {
+			x1 := (x0 + w) &^ 7
+			if x1 > r.Max.X {
+				x1 = r.Max.X
+			}
+			s := Rect(x0, y, x1, y+1)
+			n, err := i.Unload(s, tmp)
+			if err != nil {
+				return nil, err
+			}
+			off := (x0*i.Depth)>>3 - (r.Min.X*i.Depth)>>3
+			copy(line[off:], tmp[:n])
+			x0 = x1
+		}
+	}
+	return data, nil
+}
+
+// Cload loads compressed image data into r of the image. The data
+// is a sequence of blocks as in a compressed image file, each a 2*12
+// byte header giving the block's maximum y and byte count followed by
+// the compressed lines. It returns the number of bytes used.
+// Port of 9front cloadimage().
 func (i *Image) Cload(r Rectangle, data []byte) (int, error) {
 	if i == nil || i.Display == nil {
 		return 0, fmt.Errorf("cloadimage: nil image or display")
 	}
-	d := i.Display
-
-	d.mu.Lock()
-	defer d.mu.Unlock()
+	if !r.In(i.R) {
+		return 0, fmt.Errorf("cloadimage: bad rectangle")
+	}
 
-	chunk := d.bufsize - 64
-	ndata := 0
-	for len(data) > 0 && r.Max.Y > r.Min.Y {
-		n := len(data)
-		if n > chunk {
-			n = chunk
+	m := 0
+	ncblock := CompBlockSize(r, i.Depth)
+	for miny := r.Min.Y; miny != r.Max.Y; {
+		if len(data) < 2*12 {
+			return m, fmt.Errorf("cloadimage: short block header")
+		}
+		maxy := atoi(string(data[0:12]))
+		nb := atoi(string(data[12:24]))
+		if maxy <= miny || r.Max.Y < maxy {
+			return m, fmt.Errorf("cloadimage: bad maxy %d", maxy)
 		}
+		data = data[2*12:]
+		m += 2 * 12
+		if nb <= 0 || ncblock < nb || nb > len(data) {
+			return m, fmt.Errorf("cloadimage: bad count %d", nb)
+		}
+		if err := i.cloadblock(Rect(r.Min.X, miny, r.Max.X, maxy), data[:nb]); err != nil {
+			return m, err
+		}
+		miny = maxy
+		data = data[nb:]
+		m += nb
+	}
+	return m, nil
+}
 
-		a, err := d.bufimage(21 + n)
+// cloadblock sends one block of compressed lines covering r in a
+// 'Y' message. A block too big for one message, as wide lines give,
+// is expanded here and loaded uncompressed instead.
+func (i *Image) cloadblock(r Rectangle, block []byte) error {
+	d := i.Display
+	if 21+len(block) > d.bufsize {
+		buf, _, err := uncompress(block, bytesPerLine(r, i.Depth), r.Dy())
 		if err != nil {
-			return ndata, err
+			return fmt.Errorf("cloadimage: %v", err)
 		}
-		a[0] = 'Y'
-		bplong(a[1:], uint32(i.id))
-		bplong(a[5:], uint32(r.Min.X))
-		bplong(a[9:], uint32(r.Min.Y))
-		bplong(a[13:], uint32(r.Max.X))
-		bplong(a[17:], uint32(r.Max.Y))
-		copy(a[21:], data[:n])
-
-		ndata += n
-		data = data[n:]
+		_, err = i.Load(r, buf)
+		return err
 	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	return ndata, nil
+	a, err := d.bufimage(21 + len(block))
+	if err != nil {
+		return fmt.Errorf("cloadimage: %v", err)
+	}
+	a[0] = 'Y'
+	bplong(a[1:], uint32(i.id))
+	bplong(a[5:], uint32(r.Min.X))
+	bplong(a[9:], uint32(r.Min.Y))
+	bplong(a[13:], uint32(r.Max.X))
+	bplong(a[17:], uint32(r.Max.Y))
+	copy(a[21:], block)
+	return nil
 }
@@ -24,7 +24,7 @@ func (d *Display) SetCursor(c *Cursor) error {
 		return d.setCursorDefault()
 	}
 
-	cctl, err := os.OpenFile("/dev/cursor", os.O_WRONLY, 0)
+	cctl, err := os.OpenFile(d.devpath("cursor"), os.O_WRONLY, 0)
 	if err != nil {
 		return err
 	}
@@ -51,7 +51,7 @@ func (d *Display) SetCursor2(c *Cursor, c2 *Cursor2) error {
 
 // setCursorDefault resets to the default cursor.
 func (d *Display) setCursorDefault() error {
-	cctl, err := os.OpenFile("/dev/cursor", os.O_WRONLY, 0)
+	cctl, err := os.OpenFile(d.devpath("cursor"), os.O_WRONLY, 0)
 	if err != nil {
 		return err
 	}
@@ -114,4 +114,55 @@ var (
 			0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
 		},
 	}
+
+	// I-beam cursor for text
+	IBeamCursor = &Cursor{
+		Offset: Pt(-8, -7),
+		Clr: [2 * 16]byte{
+			0x1F, 0x7C, 0x11, 0xC4, 0x1E, 0x3C, 0x01, 0x40,
+			0x01, 0x40, 0x01, 0x40, 0x01, 0x40, 0x01, 0x40,
+			0x01, 0x40, 0x01, 0x40, 0x01, 0x40, 0x01, 0x40,
+			0x01, 0x40, 0x1E, 0x3C, 0x11, 0xC4, 0x1F, 0x7C,
+		},
+		Set: [2 * 16]byte{
+			0x00, 0x00, 0x0E, 0x38, 0x01, 0xC0, 0x00, 0x80,
+			0x00, 0x80, 0x00, 0x80, 0x00, 0x80, 0x00, 0x80,
+			0x00, 0x80, 0x00, 0x80, 0x00, 0x80, 0x00, 0x80,
+			0x00, 0x80, 0x01, 0xC0, 0x0E, 0x38, 0x00, 0x00,
+		},
+	}
+
+	// Horizontal resize cursor
+	HResizeCursor = &Cursor{
+		Offset: Pt(-7, -7),
+		Clr: [2 * 16]byte{
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x18, 0x18, 0x28, 0x14, 0x4F, 0xF2, 0x80, 0x01,
+			0x80, 0x01, 0x4F, 0xF2, 0x28, 0x14, 0x18, 0x18,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+		Set: [2 * 16]byte{
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x10, 0x08, 0x30, 0x0C, 0x7F, 0xFE,
+			0x7F, 0xFE, 0x30, 0x0C, 0x10, 0x08, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+	}
+
+	// Vertical resize cursor
+	VResizeCursor = &Cursor{
+		Offset: Pt(-7, -7),
+		Clr: [2 * 16]byte{
+			0x01, 0x80, 0x02, 0x40, 0x04, 0x20, 0x08, 0x10,
+			0x0E, 0x70, 0x02, 0x40, 0x02, 0x40, 0x02, 0x40,
+			0x02, 0x40, 0x02, 0x40, 0x02, 0x40, 0x0E, 0x70,
+			0x08, 0x10, 0x04, 0x20, 0x02, 0x40, 0x01, 0x80,
+		},
+		Set: [2 * 16]byte{
+			0x00, 0x00, 0x01, 0x80, 0x03, 0xC0, 0x07, 0xE0,
+			0x01, 0x80, 0x01, 0x80, 0x01, 0x80, 0x01, 0x80,
+			0x01, 0x80, 0x01, 0x80, 0x01, 0x80, 0x01, 0x80,
+			0x07, 0xE0, 0x03, 0xC0, 0x01, 0x80, 0x00, 0x00,
+		},
+	}
 )
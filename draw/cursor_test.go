@@ -0,0 +1,47 @@
+package draw
+
+import "testing"
+
+// TestCursorShapes checks that each predefined cursor is drawn at its
+// hot spot.
+func TestCursorShapes(t *testing.T) {
+	for name, c := range map[string]*Cursor{
+		"arrow":   ArrowCursor,
+		"cross":   CrossCursor,
+		"ibeam":   IBeamCursor,
+		"hresize": HResizeCursor,
+		"vresize": VResizeCursor,
+	} {
+		x, y := -c.Offset.X, -c.Offset.Y
+		if x < 0 || x >= 16 || y < 0 || y >= 16 {
+			continue
+		}
+		bit := byte(0x80 >> uint(x%8))
+		if (c.Set[2*y+x/8]|c.Clr[2*y+x/8])&bit == 0 {
+			t.Errorf("%s: nothing drawn at the hot spot", name)
+		}
+	}
+}
+
+// TestIBeamSymmetric checks that the I-beam's stem is under the middle
+// of its serifs, at the hot spot.
+func TestIBeamSymmetric(t *testing.T) {
+	c := IBeamCursor
+	mid := -c.Offset.X
+	bit := func(b [2 * 16]byte, x, y int) bool {
+		return x >= 0 && x < 16 && b[2*y+x/8]&(0x80>>uint(x%8)) != 0
+	}
+	for y := 0; y < 16; y++ {
+		for d := 1; d < 16; d++ {
+			if bit(c.Set, mid-d, y) != bit(c.Set, mid+d, y) || bit(c.Clr, mid-d, y) != bit(c.Clr, mid+d, y) {
+				t.Errorf("row %d not symmetric about the hot spot column %d", y, mid)
+				break
+			}
+		}
+	}
+	for y := 3; y < 13; y++ {
+		if !bit(c.Set, mid, y) {
+			t.Errorf("row %d: stem not at column %d", y, mid)
+		}
+	}
+}
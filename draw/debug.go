@@ -0,0 +1,107 @@
+package draw
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// checkdraw validates the arguments of a draw call when d.Debug is
+// set, reporting anything that would make it draw nothing or draw
+// from undefined pixels. Called without d.mu held.
+func (d *Display) checkdraw(dst *Image, r Rectangle, src *Image, sp Point, mask *Image, mp Point) {
+	var probs []string
+	d.mu.Lock()
+	for _, x := range []struct {
+		name string
+		i    *Image
+	}{{"dst", dst}, {"src", src}, {"mask", mask}} {
+		switch {
+		case x.i == nil:
+			probs = append(probs, fmt.Sprintf("%s image is nil", x.name))
+		case x.i.Display == nil:
+			probs = append(probs, fmt.Sprintf("%s image %d has been freed", x.name, x.i.id))
+		case x.i.Display != d:
+			probs = append(probs, fmt.Sprintf("%s image %d belongs to another display", x.name, x.i.id))
+		case x.i != d.Image && d.track.live[x.i.id] == nil:
+			probs = append(probs, fmt.Sprintf("%s image %d is not allocated", x.name, x.i.id))
+		}
+	}
+	d.mu.Unlock()
+
+	cr, ok := r.Clip(dst.Clipr)
+	if ok {
+		cr, ok = cr.Clip(dst.R)
+	}
+	if !ok {
+		probs = append(probs, fmt.Sprintf("rectangle %v is outside dst %v clipped to %v", r, dst.R, dst.Clipr))
+	} else {
+		if p := uncovered("src", src, cr.Add(sp.Sub(r.Min))); p != "" {
+			probs = append(probs, p)
+		}
+		if p := uncovered("mask", mask, cr.Add(mp.Sub(r.Min))); p != "" {
+			probs = append(probs, p)
+		}
+	}
+	for _, p := range probs {
+		d.debugf("draw: %s", p)
+	}
+}
+
+// uncovered describes how the non-replicated image i fails to cover
+// r, or returns "" if it covers it.
+func uncovered(name string, i *Image, r Rectangle) string {
+	if i == nil || i.Repl {
+		return ""
+	}
+	if r.In(i.R) && r.In(i.Clipr) {
+		return ""
+	}
+	return fmt.Sprintf("%s %v clipped to %v does not cover %v", name, i.R, i.Clipr, r)
+}
+
+// debugf reports a problem found in debug mode, with the call site
+// outside this package that caused it, through d.Error, or on
+// standard error if there is no handler.
+func (d *Display) debugf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if file, line := callsite(); file != "" {
+		msg = fmt.Sprintf("%s (%s:%d)", msg, file, line)
+	}
+	if d.Error != nil {
+		d.Error(msg)
+		return
+	}
+	fmt.Fprintln(os.Stderr, msg)
+}
+
+// callsite returns the position of the innermost caller outside this
+// package, or "" if there is none.
+func callsite() (string, int) {
+	pc := make([]uintptr, 32)
+	n := runtime.Callers(2, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		f, more := frames.Next()
+		if !inPackage(f) {
+			return f.File, f.Line
+		}
+		if !more {
+			return "", 0
+		}
+	}
+}
+
+// pkgdir is the source directory of this package.
+var pkgdir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// inPackage reports whether f is in the library code of this package.
+// Frames in its tests count as callers.
+func inPackage(f runtime.Frame) bool {
+	return filepath.Dir(f.File) == pkgdir && !strings.HasSuffix(f.File, "_test.go")
+}
@@ -0,0 +1,83 @@
+package draw
+
+import (
+	"strings"
+	"testing"
+)
+
+// debugDisplay returns a buffering display in debug mode that collects
+// its reports, with a 100x100 display image and White and Opaque.
+func debugDisplay(t *testing.T) (*Display, *[]string) {
+	d := bufDisplay()
+	var msgs []string
+	d.Debug = true
+	d.Error = func(s string) { msgs = append(msgs, s) }
+	d.Image = &Image{Display: d, Pix: RGB24, Depth: 24, R: Rect(0, 0, 100, 100), Clipr: Rect(0, 0, 100, 100)}
+	var err error
+	if d.White, err = d.AllocImage(Rect(0, 0, 1, 1), GREY1, true, DWhite); err != nil {
+		t.Fatal(err)
+	}
+	d.Opaque = d.White
+	d.Black = d.White
+	return d, &msgs
+}
+
+func TestCheckdrawClean(t *testing.T) {
+	d, msgs := debugDisplay(t)
+	src, _ := d.AllocImage(Rect(0, 0, 20, 20), RGB24, false, DRed)
+	d.Image.Draw(Rect(10, 10, 30, 30), src, ZP)
+	d.Image.Draw(Rect(0, 0, 100, 100), d.White, ZP)
+	if len(*msgs) != 0 {
+		t.Errorf("valid draws reported %q", *msgs)
+	}
+}
+
+func TestCheckdrawProblems(t *testing.T) {
+	tests := []struct {
+		name string
+		draw func(d *Display)
+		want string
+	}{
+		{"outside dst", func(d *Display) {
+			d.Image.Draw(Rect(200, 200, 210, 210), d.White, ZP)
+		}, "outside dst"},
+		{"clipped away", func(d *Display) {
+			d.Image.Clipr = Rect(0, 0, 10, 10)
+			d.Image.Draw(Rect(50, 50, 60, 60), d.White, ZP)
+		}, "outside dst"},
+		{"freed src", func(d *Display) {
+			src, _ := d.AllocImage(Rect(0, 0, 10, 10), RGB24, false, DRed)
+			src.Free()
+			src.Display = d // stale pointer kept by a caller
+			d.Image.Draw(Rect(0, 0, 10, 10), src, ZP)
+		}, "not allocated"},
+		{"src too small", func(d *Display) {
+			src, _ := d.AllocImage(Rect(0, 0, 10, 10), RGB24, false, DRed)
+			d.Image.Draw(Rect(0, 0, 20, 20), src, ZP)
+		}, "does not cover"},
+		{"mask offset", func(d *Display) {
+			m, _ := d.AllocImage(Rect(0, 0, 10, 10), GREY8, false, DWhite)
+			d.Image.GenDraw(Rect(0, 0, 10, 10), d.White, ZP, m, Pt(5, 5))
+		}, "mask"},
+		{"other display", func(d *Display) {
+			e := bufDisplay()
+			src, _ := e.AllocImage(Rect(0, 0, 1, 1), GREY1, true, DWhite)
+			d.Image.Draw(Rect(0, 0, 10, 10), src, ZP)
+		}, "another display"},
+	}
+	for _, tt := range tests {
+		d, msgs := debugDisplay(t)
+		tt.draw(d)
+		if len(*msgs) == 0 {
+			t.Errorf("%s: nothing reported", tt.name)
+			continue
+		}
+		m := (*msgs)[0]
+		if !strings.Contains(m, tt.want) {
+			t.Errorf("%s: report %q does not mention %q", tt.name, m, tt.want)
+		}
+		if !strings.Contains(m, "debug_test.go:") {
+			t.Errorf("%s: report %q lacks the call site", tt.name, m)
+		}
+	}
+}
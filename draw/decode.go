@@ -0,0 +1,90 @@
+package draw
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for DecodeImage
+	_ "image/jpeg" // register JPEG for DecodeImage
+	_ "image/png"  // register PNG for DecodeImage
+	"io"
+)
+
+// DecodeImage decodes an image in any format registered with the
+// image package (PNG, JPEG and GIF are always available) and loads it
+// onto display d. If format is not empty the data must be in that
+// format, named as image.Decode names it ("png", "jpeg", "gif").
+// The image is RGB24 if it is opaque and RGBA32 otherwise, with the
+// same bounds as the decoded image.
+func DecodeImage(d *Display, r io.Reader, format string) (*Image, error) {
+	m, f, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("decodeimage: %v", err)
+	}
+	if format != "" && f != format {
+		return nil, fmt.Errorf("decodeimage: image is %s, not %s", f, format)
+	}
+	return d.FromImage(m)
+}
+
+// FromImage allocates an image on d and loads the pixels of m into it.
+// See DecodeImage.
+func (d *Display) FromImage(m image.Image) (*Image, error) {
+	b := m.Bounds()
+	r := Rect(b.Min.X, b.Min.Y, b.Max.X, b.Max.Y)
+	pix := RGBA32
+	if isOpaque(m) {
+		pix = RGB24
+	}
+	data := packRGBA(pix, m)
+	i, err := d.AllocImage(r, pix, false, DNofill)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := i.Load(r, data); err != nil {
+		i.Free()
+		return nil, err
+	}
+	return i, nil
+}
+
+// isOpaque reports whether every pixel of m is fully opaque.
+func isOpaque(m image.Image) bool {
+	if o, ok := m.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := m.At(x, y).RGBA(); a != 0xFFFF {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// packRGBA converts m to pixel data laid out as Load expects it, in
+// RGB24 or RGBA32. It is the inverse of unpackRGBA for those formats.
+// Color values are premultiplied, as Plan 9 expects.
+func packRGBA(pix Pix, m image.Image) []byte {
+	b := m.Bounds()
+	nb := chantodepth(pix) / 8
+	data := make([]byte, b.Dx()*b.Dy()*nb)
+	o := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			cr, cg, cb, ca := m.At(x, y).RGBA()
+			// Pixels are little-endian with the last channel of the
+			// descriptor in the least significant byte.
+			if pix == RGBA32 {
+				data[o] = uint8(ca >> 8)
+				o++
+			}
+			data[o+0] = uint8(cb >> 8)
+			data[o+1] = uint8(cg >> 8)
+			data[o+2] = uint8(cr >> 8)
+			o += 3
+		}
+	}
+	return data
+}
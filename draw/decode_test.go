@@ -0,0 +1,84 @@
+package draw
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func TestDecodeImage(t *testing.T) {
+	d := memDisplay(t, RGB24)
+
+	// Translucent: RGBA32, premultiplied, with the bounds kept.
+	m := image.NewNRGBA(image.Rect(3, 4, 13, 10))
+	for y := 4; y < 10; y++ {
+		for x := 3; x < 13; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{uint8(x * 20), uint8(y * 20), 0xFF, uint8(x * 16)})
+		}
+	}
+	// PNG does not keep the origin, so load m directly to check bounds.
+	i, err := d.FromImage(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.Pix != RGBA32 || i.R != Rect(3, 4, 13, 10) {
+		t.Fatalf("got %s %v, want %s %v", chantostr(i.Pix), i.R, chantostr(RGBA32), Rect(3, 4, 13, 10))
+	}
+	got, err := i.Capture(i.R)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 4; y < 10; y++ {
+		for x := 3; x < 13; x++ {
+			if got.RGBAAt(x, y) != color.RGBAModel.Convert(m.At(x, y)) {
+				t.Fatalf("pixel %d,%d = %v, want %v", x, y, got.RGBAAt(x, y), m.At(x, y))
+			}
+		}
+	}
+
+	// Opaque and large enough to need several load messages: RGB24.
+	o := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	for k := range o.Pix {
+		o.Pix[k] = uint8(k * 7)
+		if k%4 == 3 {
+			o.Pix[k] = 0xFF
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, o)
+	i, err = DecodeImage(d, &buf, "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.Pix != RGB24 {
+		t.Errorf("opaque image is %s, want %s", chantostr(i.Pix), chantostr(RGB24))
+	}
+	got, _ = i.Capture(i.R)
+	if !bytes.Equal(got.Pix, o.Pix) {
+		t.Error("pixels differ")
+	}
+
+	// GIF.
+	p := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White})
+	p.SetColorIndex(1, 2, 1)
+	buf.Reset()
+	gif.Encode(&buf, p, nil)
+	i, err = DecodeImage(d, bytes.NewReader(buf.Bytes()), "gif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ = i.Capture(i.R)
+	if got.RGBAAt(1, 2) != (color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}) || got.RGBAAt(0, 0) != (color.RGBA{0, 0, 0, 0xFF}) {
+		t.Error("gif pixels differ")
+	}
+
+	if _, err := DecodeImage(d, bytes.NewReader(buf.Bytes()), "png"); err == nil {
+		t.Error("gif accepted as png")
+	}
+	if _, err := DecodeImage(d, bytes.NewReader([]byte("not an image")), ""); err == nil {
+		t.Error("garbage decoded")
+	}
+}
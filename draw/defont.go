@@ -1,50 +1,113 @@
 package draw
 
-// getdefont returns the built-in default font.
-func (d *Display) getdefont() *Subfont {
-	// Create a minimal built-in font for when no font files are available
-	// This is a simple fixed-width bitmap font
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defontText holds the glyphs of the built-in font in the plain-text
+// form described at the top of defont.txt.
+//
+//go:embed defont.txt
+var defontText string
 
-	// Create a small 8x16 character set for ASCII
-	charWidth := 8
-	charHeight := 16
-	nchars := 128
+// Geometry of the built-in font. Each glyph is defontCols by
+// defontRows pixels, drawn in a cell one column wider so that
+// adjacent characters do not touch, with defontPad blank rows
+// above it.
+const (
+	defontN      = 128
+	defontCols   = 5
+	defontRows   = 9
+	defontWidth  = defontCols + 1
+	defontPad    = 2
+	defontHeight = defontPad + defontRows + 1
+	defontAscent = defontPad + 7
+)
 
-	// Allocate an image for the font glyphs
-	width := charWidth * nchars
-	img, err := d.AllocImage(Rect(0, 0, width, charHeight), GREY1, false, DWhite)
+// getdefont returns the built-in default font, or nil if its glyph
+// image cannot be allocated. It plays the role of 9front getdefont(),
+// but the font is not 9front's: it is a 5×9 pixel font covering ASCII,
+// drawn from the text in defont.txt.
+func (d *Display) getdefont() *Subfont {
+	bits, info, err := parseDefont(defontText)
 	if err != nil {
 		return nil
 	}
-
-	// Build fontchar info
-	info := make([]Fontchar, nchars+1)
-	for i := 0; i <= nchars; i++ {
-		info[i] = Fontchar{
-			X:      i * charWidth,
-			Top:    0,
-			Bottom: byte(charHeight),
-			Left:   0,
-			Width:  byte(charWidth),
-		}
+	img, err := d.AllocImage(Rect(0, 0, defontN*defontWidth, defontHeight), GREY1, false, DBlack)
+	if err != nil {
+		return nil
+	}
+	if _, err := img.Load(img.R, bits); err != nil {
+		img.Free()
+		return nil
 	}
-
 	return &Subfont{
 		Name:   "*default*",
-		N:      nchars,
-		Height: charHeight,
-		Ascent: charHeight - 4,
+		N:      defontN,
+		Height: defontHeight,
+		Ascent: defontAscent,
 		Info:   info,
 		Bits:   img,
 		ref:    1,
 	}
 }
 
-// Default font data - a minimal bitmap font
-// This would normally contain actual glyph bitmaps
-var defaultFontData = []byte{
-	// Font header: height, ascent
-	16, 12,
-	// Character data would go here
-	// For now, using a placeholder
+// parseDefont converts the text glyph description into GREY1 pixel
+// data for the subfont image and the matching character info.
+// Characters not described have zero width, so loadchar draws the
+// glyph for character 0 in their place.
+func parseDefont(text string) ([]byte, []Fontchar, error) {
+	r := Rect(0, 0, defontN*defontWidth, defontHeight)
+	bpl := bytesPerLine(r, 1)
+	bits := make([]byte, bpl*defontHeight)
+	info := make([]Fontchar, defontN+1)
+	for i := range info {
+		info[i].X = i * defontWidth
+	}
+
+	c := -1 // character being read, -1 before the first
+	row := 0
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || line[0] == '#' && c < 0 {
+			continue
+		}
+		if strings.HasPrefix(line, "char ") {
+			if c >= 0 && row != defontRows {
+				return nil, nil, fmt.Errorf("defont: line %d: char %#x has %d rows", n+1, c, row)
+			}
+			v, err := strconv.ParseUint(strings.TrimSpace(line[5:]), 0, 32)
+			if err != nil || v >= defontN {
+				return nil, nil, fmt.Errorf("defont: line %d: bad char %q", n+1, line)
+			}
+			c = int(v)
+			row = 0
+			info[c].Top = defontPad
+			info[c].Bottom = defontPad + defontRows
+			info[c].Width = defontWidth
+			continue
+		}
+		if c < 0 || row >= defontRows || len(line) != defontCols {
+			return nil, nil, fmt.Errorf("defont: line %d: unexpected %q", n+1, line)
+		}
+		y := defontPad + row
+		for x, ch := range line {
+			switch ch {
+			case '#':
+				px := c*defontWidth + x
+				bits[y*bpl+px>>3] |= 0x80 >> uint(px&7)
+			case '.':
+			default:
+				return nil, nil, fmt.Errorf("defont: line %d: bad pixel %q", n+1, ch)
+			}
+		}
+		row++
+	}
+	if c >= 0 && row != defontRows {
+		return nil, nil, fmt.Errorf("defont: char %#x has %d rows", c, row)
+	}
+	return bits, info, nil
 }
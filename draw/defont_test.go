@@ -0,0 +1,82 @@
+package draw
+
+import (
+	"strings"
+	"testing"
+)
+
+// defontPixel reports whether the pixel at (x, y) is set in GREY1
+// subfont data laid out as parseDefont returns it.
+func defontPixel(bits []byte, x, y int) bool {
+	bpl := bytesPerLine(Rect(0, 0, defontN*defontWidth, defontHeight), 1)
+	return bits[y*bpl+x>>3]&(0x80>>uint(x&7)) != 0
+}
+
+// TestParseDefont checks that the embedded font covers printable ASCII
+// and leaves control characters to fall back to character 0.
+func TestParseDefont(t *testing.T) {
+	bits, info, err := parseDefont(defontText)
+	if err != nil {
+		t.Fatalf("parseDefont: %v", err)
+	}
+	if len(info) != defontN+1 {
+		t.Fatalf("len(info) = %d, want %d", len(info), defontN+1)
+	}
+	if info[0].Width == 0 {
+		t.Error("character 0 has no glyph")
+	}
+	for c := 0x20; c < 0x7F; c++ {
+		if info[c].Width != defontWidth {
+			t.Errorf("char %q: width %d, want %d", rune(c), info[c].Width, defontWidth)
+		}
+	}
+	for _, c := range []int{0x01, '\n', 0x1F, 0x7F} {
+		if info[c].Width != 0 {
+			t.Errorf("char %#x: width %d, want 0", c, info[c].Width)
+		}
+	}
+	if info[defontN].X != defontN*defontWidth {
+		t.Errorf("sentinel X = %d, want %d", info[defontN].X, defontN*defontWidth)
+	}
+
+	// The top row of 'T' is solid; the spacing column is always clear.
+	x := int('T') * defontWidth
+	for i := 0; i < defontCols; i++ {
+		if !defontPixel(bits, x+i, defontPad) {
+			t.Errorf("'T' pixel (%d, %d) not set", i, defontPad)
+		}
+	}
+	for y := 0; y < defontHeight; y++ {
+		if defontPixel(bits, x+defontCols, y) {
+			t.Errorf("'T' spacing column set at row %d", y)
+		}
+	}
+	for y := 0; y < defontHeight; y++ {
+		for i := 0; i < defontWidth; i++ {
+			if defontPixel(bits, int(' ')*defontWidth+i, y) {
+				t.Fatalf("space has pixel (%d, %d) set", i, y)
+			}
+		}
+	}
+}
+
+// TestParseDefontErrors checks that malformed glyph text is rejected.
+func TestParseDefontErrors(t *testing.T) {
+	rows := strings.Repeat(".....\n", defontRows)
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"short glyph", "char 0x41\n.....\n"},
+		{"wide row", "char 0x41\n......\n" + rows},
+		{"bad pixel", "char 0x41\n..x..\n" + rows},
+		{"too many rows", "char 0x41\n" + rows + ".....\n"},
+		{"out of range", "char 0x80\n" + rows},
+		{"rows before char", ".....\n"},
+	}
+	for _, tt := range tests {
+		if _, _, err := parseDefont(tt.text); err == nil {
+			t.Errorf("%s: parseDefont succeeded, want error", tt.name)
+		}
+	}
+}
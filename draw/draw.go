@@ -3,8 +3,11 @@
 package draw
 
 import (
+	"io"
+	"io/fs"
 	"os"
 	"sync"
+	"time"
 )
 
 // Point is a location in the integer grid.
@@ -254,7 +257,7 @@ type Display struct {
 
 	// File descriptors
 	ctlfd  *os.File
-	datafd *os.File
+	datafd io.ReadWriteCloser // a *memdev for a memory display
 	reffd  *os.File
 
 	// Display info
@@ -274,25 +277,47 @@ type Display struct {
 	bufsize int // max buffer size
 	bufp    int // current position in buffer
 
-	// Default font
+	// Default font; never nil on a display returned by Init,
+	// which falls back to the built-in font if need be.
 	DefaultFont    *Font
 	DefaultSubfont *Subfont
 
 	// Image id counter
 	imageid int
 
+	// Live image records (see track.go)
+	track imagetrack
+
 	// Error handler
 	Error func(string)
 
+	// Debug enables validation of draw calls; problems are reported
+	// through Error with the offending call site (see debug.go).
+	Debug bool
+
 	// Screen DPI
 	DPI int
 
+	// How client-side color blending is done (see blend.go)
+	Blend BlendMode
+
+	// Where the snarf buffer is kept; nil means the snarf file in
+	// the device directory (see snarf.go)
+	Snarf Snarfer
+
 	// Window directory (for rio)
 	windir string
+	label  string // as last set by SetLabel
 	devdir string
 
 	// Is this a new-style display (sends screenimage id in flush)
 	isnew bool
+
+	// Refresh reader (see refresh.go)
+	refc     chan Refresh
+	refonce  sync.Once
+	stop     chan struct{} // closed by Close
+	stoponce sync.Once
 }
 
 // Screen represents a Plan 9 screen (for layers).
@@ -372,6 +397,8 @@ type Font struct {
 	subf       []Cachesubf
 	sub        []*Cachefont
 	cacheimage *Image
+	fs         fs.FS   // where subfonts are read from, if not the OS (see bundle.go)
+	ttf        *ttfont // the TrueType font rendered into subfonts, if any (see ttf.go)
 }
 
 // Subfont is a collection of character glyphs forming part of a font.
@@ -401,6 +428,7 @@ type Cachefont struct {
 	Offset      int    // index offset to add
 	Name        string // file name
 	Subfontname string
+	font        *Font // the fallback font the range is from, if any
 }
 
 // Cacheinfo describes a cached glyph.
@@ -432,16 +460,36 @@ type Mousectl struct {
 	C       chan Mouse
 	Resize  chan bool
 	Display *Display
-	file    *os.File // mouse fd
-	cfd     *os.File // cursor fd
-	image   *Image   // associated window/display image
+	file    *os.File      // mouse fd
+	cfd     *os.File      // cursor fd
+	image   *Image        // associated window/display image
+	done    chan struct{} // closed when readproc exits
+	stop    chan struct{} // closed by Close
+	once    sync.Once     // guards Close
+
+	mu     sync.Mutex // guards the fields below
+	feed   chan Mouse // lossless feed to a MouseReader, replacing C
+	exited bool       // readproc has exited
 }
 
 // Keyboardctl provides access to keyboard events.
 type Keyboardctl struct {
 	C     chan rune
+	Ev    chan KeyEvent // key presses and releases; nil unless opened with InitKbd
 	file  *os.File
 	ctlfd *os.File
+	done  chan struct{} // closed when readproc exits
+	once  sync.Once     // guards Close
+
+	// Key repeat state (see repeat.go)
+	mu      sync.Mutex // guards the fields below and sends from the timer
+	delay   time.Duration
+	rate    time.Duration
+	rep     *time.Timer
+	repgen  uint // counts repeats started, to spot stale timers
+	reprune rune
+	closed  bool
+	mod     Mod // modifiers held, from the kbd device
 }
 
 // Menu for menuhit.
@@ -34,6 +34,9 @@ func (dst *Image) gendrawop(r Rectangle, src *Image, sp Point, mask *Image, mp P
 	if mask == nil {
 		mask = d.Opaque
 	}
+	if d.Debug {
+		d.checkdraw(dst, r, src, sp, mask, mp)
+	}
 
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -22,26 +22,67 @@ func (dst *Image) FillEllipseOp(c Point, a, b int, src *Image, sp Point, op Op)
 }
 
 // Arc draws an arc of an ellipse centered at c with semi-axes a and b.
-// The arc extends from angle alpha to alpha+phi, measured in degrees.
+// The arc starts at angle alpha and sweeps phi degrees, with angles
+// measured counterclockwise from the positive x axis (3 o'clock), as
+// in 9front. A negative phi sweeps clockwise, a phi of zero draws
+// nothing, and a sweep of 360 degrees or more draws the whole ellipse.
 func (dst *Image) Arc(c Point, a, b, thick int, src *Image, sp Point, alpha, phi int) {
 	dst.ArcOp(c, a, b, thick, src, sp, alpha, phi, SoverD)
 }
 
 // ArcOp is Arc with a compositing operator.
 func (dst *Image) ArcOp(c Point, a, b, thick int, src *Image, sp Point, alpha, phi int, op Op) {
-	alpha |= 1 << 31
-	dst.doellipse('e', c, a, b, thick, src, sp, alpha, phi, op)
+	alpha, phi, ok := normarc(alpha, phi)
+	switch {
+	case !ok:
+		return
+	case phi >= 360:
+		dst.doellipse('e', c, a, b, thick, src, sp, 0, 0, op)
+	default:
+		dst.doellipse('e', c, a, b, thick, src, sp, alpha|1<<31, phi, op)
+	}
 }
 
-// FillArc fills an arc (pie slice) of an ellipse.
+// FillArc fills an arc (pie slice) of an ellipse. The angles are
+// interpreted as for Arc; a full sweep fills the whole ellipse.
 func (dst *Image) FillArc(c Point, a, b int, src *Image, sp Point, alpha, phi int) {
 	dst.FillArcOp(c, a, b, src, sp, alpha, phi, SoverD)
 }
 
 // FillArcOp is FillArc with a compositing operator.
 func (dst *Image) FillArcOp(c Point, a, b int, src *Image, sp Point, alpha, phi int, op Op) {
-	alpha |= 1 << 31
-	dst.doellipse('E', c, a, b, 0, src, sp, alpha, phi, op)
+	alpha, phi, ok := normarc(alpha, phi)
+	switch {
+	case !ok:
+		return
+	case phi >= 360:
+		dst.doellipse('E', c, a, b, 0, src, sp, 0, 0, op)
+	default:
+		dst.doellipse('E', c, a, b, 0, src, sp, alpha|1<<31, phi, op)
+	}
+}
+
+// normarc puts an arc into the form devdraw handles best: a start
+// angle in [0, 360) and a positive sweep, with any sweep of a full
+// turn or more reported as exactly 360. A negative sweep becomes the
+// equivalent positive one ending at alpha, as memarc does. It reports
+// false for a zero sweep, which draws nothing.
+func normarc(alpha, phi int) (int, int, bool) {
+	if phi == 0 {
+		return 0, 0, false
+	}
+	if phi < 0 {
+		alpha += phi
+		phi = -phi
+	}
+	if phi >= 360 {
+		return 0, 360, true
+	}
+	alpha %= 360
+	if alpha < 0 {
+		alpha += 360
+	}
+	return alpha, phi, true
 }
 
 func (dst *Image) doellipse(cmd byte, c Point, xr, yr, thick int, src *Image, sp Point, alpha, phi int, op Op) {
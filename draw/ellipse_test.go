@@ -0,0 +1,77 @@
+package draw
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestNormarc(t *testing.T) {
+	tests := []struct {
+		alpha, phi int
+		wa, wp     int
+		ok         bool
+	}{
+		{0, 90, 0, 90, true},
+		{45, 90, 45, 90, true},
+		{90, -45, 45, 45, true},    // clockwise sweep ending at 90
+		{0, -90, 270, 90, true},    // wraps below zero
+		{-30, 60, 330, 60, true},   // negative start
+		{370, 10, 10, 10, true},    // start past a full turn
+		{-720, 10, 0, 10, true},    // several turns negative
+		{0, 0, 0, 0, false},        // zero sweep draws nothing
+		{123, 360, 0, 360, true},   // full circle
+		{10, 1000, 0, 360, true},   // more than a full circle
+		{10, -360, 0, 360, true},   // full circle clockwise
+		{359, 359, 359, 359, true}, // largest partial sweep
+	}
+	for _, tt := range tests {
+		a, p, ok := normarc(tt.alpha, tt.phi)
+		if a != tt.wa || p != tt.wp || ok != tt.ok {
+			t.Errorf("normarc(%d, %d) = %d, %d, %v; want %d, %d, %v",
+				tt.alpha, tt.phi, a, p, ok, tt.wa, tt.wp, tt.ok)
+		}
+	}
+}
+
+// arcmsg draws with fn on a display that only buffers and returns the
+// bytes it would send.
+func arcmsg(fn func(dst, src *Image)) []byte {
+	d := &Display{bufsize: 1000}
+	d.buf = make([]byte, d.bufsize+5)
+	dst := &Image{Display: d, id: 1}
+	src := &Image{Display: d, id: 2}
+	fn(dst, src)
+	return d.buf[:d.bufp]
+}
+
+func TestArcMessage(t *testing.T) {
+	m := arcmsg(func(dst, src *Image) {
+		dst.Arc(Pt(10, 10), 5, 5, 0, src, ZP, 90, -45)
+	})
+	if len(m) != 45 || m[0] != 'e' {
+		t.Fatalf("Arc message = %x, want 45-byte 'e'", m)
+	}
+	if alpha := binary.LittleEndian.Uint32(m[37:]); alpha != 1<<31|45 {
+		t.Errorf("alpha = %#x, want %#x", alpha, uint32(1<<31|45))
+	}
+	if phi := binary.LittleEndian.Uint32(m[41:]); phi != 45 {
+		t.Errorf("phi = %d, want 45", phi)
+	}
+
+	m = arcmsg(func(dst, src *Image) {
+		dst.FillArc(Pt(10, 10), 5, 5, src, ZP, 30, 720)
+	})
+	if len(m) != 45 || m[0] != 'E' {
+		t.Fatalf("FillArc message = %x, want 45-byte 'E'", m)
+	}
+	if alpha, phi := binary.LittleEndian.Uint32(m[37:]), binary.LittleEndian.Uint32(m[41:]); alpha != 0 || phi != 0 {
+		t.Errorf("full FillArc alpha, phi = %#x, %d; want plain ellipse", alpha, phi)
+	}
+
+	m = arcmsg(func(dst, src *Image) {
+		dst.Arc(Pt(10, 10), 5, 5, 0, src, ZP, 30, 0)
+	})
+	if len(m) != 0 {
+		t.Errorf("zero-sweep Arc sent %x, want nothing", m)
+	}
+}
@@ -35,6 +35,7 @@ type Eventctl struct {
 
 // Einit initializes the event system.
 // keys is a mask of Emouse and/or Ekeyboard.
+// The mouse and keyboard are opened in the display's device directory.
 func (d *Display) Einit(keys int) (*Eventctl, error) {
 	ec := &Eventctl{
 		Display: d,
@@ -43,13 +44,13 @@ func (d *Display) Einit(keys int) (*Eventctl, error) {
 
 	var err error
 	if keys&Emouse != 0 {
-		ec.Mouse, err = InitMouse("", d.Image)
+		ec.Mouse, err = InitMouse(d.devpath("mouse"), d.Image)
 		if err != nil {
 			return nil, err
 		}
 	}
 	if keys&Ekeyboard != 0 {
-		ec.Keyboard, err = InitKeyboard("")
+		ec.Keyboard, err = InitKeyboard(d.devpath("cons"))
 		if err != nil {
 			if ec.Mouse != nil {
 				ec.Mouse.Close()
@@ -81,7 +82,10 @@ func (ec *Eventctl) Eread(keys int, ev *Event) int {
 				ev.Kbdc = r
 				return Ekeyboard
 			}
-		case <-ec.Mouse.Resize:
+		case _, ok := <-ec.Mouse.Resize:
+			if !ok {
+				return 0
+			}
 			// Handle resize
 			ec.Display.GetWindow(Refnone)
 			ec.Screen = ec.Display.Image
@@ -269,6 +269,10 @@ func (f *Font) cachechars(s *string, r *[]rune, cp []uint16, max int) (int, int,
 // cf2subfont loads a subfont for a Cachefont entry.
 // Port of 9front cf2subfont().
 func cf2subfont(cf *Cachefont, f *Font) *Subfont {
+	if cf.font != nil {
+		// A range of a fallback font.
+		f = cf.font
+	}
 	name := cf.Subfontname
 	if name == "" {
 		depth := 8
@@ -285,28 +289,44 @@ func cf2subfont(cf *Cachefont, f *Font) *Subfont {
 	if sf != nil {
 		return sf
 	}
-	// Try to open from file
+	// Render it from a TrueType font, or open it from file, in the
+	// font's bundle if it has one
 	if f.Display != nil {
-		sf = f.Display.openSubfont(name)
+		if f.ttf != nil {
+			return f.ttf.subfont(f.Display, cf)
+		}
+		if f.fs != nil {
+			sf = f.Display.openSubfontFS(f.fs, name)
+		}
+		if sf == nil {
+			sf = f.Display.openSubfont(name)
+		}
 	}
 	return sf
 }
 
 // loadchar loads a glyph from subfont into cache and sends 'l' to devdraw.
 // Port of 9front loadchar(). Returns 1 on success, 0 on failure, -1 on retry needed.
+//
+// A character missing from the subfont of the first range holding it
+// is looked for in later ranges, which include those of any fallback
+// fonts, before PJW is drawn in its place.
 func (f *Font) loadchar(r rune, c *Cacheinfo, h int, noflush int) (int, *string) {
 	pic := r
+	next := 0 // where to resume the search of f.sub
 
 Again:
 	var cf *Cachefont
-	for i := 0; i < f.nsub; i++ {
-		cf = f.sub[i]
+	for next < f.nsub {
+		cf = f.sub[next]
+		next++
 		if cf.Min <= int(pic) && int(pic) <= cf.Max {
 			goto Found
 		}
 	}
 	if pic != PJW {
 		pic = PJW
+		next = 0
 		goto Again
 	}
 	return 0, nil
@@ -349,19 +369,17 @@ Found:
 	subf.f = cf2subfont(cf, f)
 	if subf.f == nil {
 		if cf.Subfontname == "" {
-			if pic != PJW {
-				pic = PJW
-				goto Again
-			}
-			return 0, nil
+			goto Again
 		}
 		sfn := cf.Subfontname
 		return -1, &sfn
 	}
 	subf.cf = cf
 
-	// Adjust ascent if subfont has larger ascent than font
-	if subf.f.Ascent > f.Ascent && f.Display != nil {
+	// Adjust ascent if subfont has larger ascent than font.
+	// A fallback's subfont is shared with the fallback font, so it is
+	// left alone and its glyphs clipped as they are loaded instead.
+	if subf.f.Ascent > f.Ascent && f.Display != nil && cf.font == nil {
 		d := subf.f.Ascent - f.Ascent
 		b := subf.f.Bits
 		if b != nil {
@@ -390,19 +408,11 @@ Found2:
 	// Possible overflow here, but works out okay
 	idx := int(pic) + cf.Offset - cf.Min
 	if idx >= subf.f.N {
-		if pic != PJW {
-			pic = PJW
-			goto Again
-		}
-		return 0, nil
+		goto Again
 	}
 	fi := &subf.f.Info[idx]
 	if fi.Width == 0 {
-		if pic != PJW {
-			pic = PJW
-			goto Again
-		}
-		return 0, nil
+		goto Again
 	}
 
 	wid := int(subf.f.Info[idx+1].X) - int(fi.X)
@@ -444,6 +454,17 @@ Found2:
 
 	top := int(fi.Top) + (f.Ascent - subf.f.Ascent)
 	bottom := int(fi.Bottom) + (f.Ascent - subf.f.Ascent)
+	sy := int(fi.Top)
+	if top < 0 {
+		sy -= top
+		top = 0
+	}
+	if bottom > f.Height {
+		bottom = f.Height
+	}
+	if top > bottom {
+		top = bottom
+	}
 
 	b[0] = 'l'
 	bplong(b[1:], uint32(f.cacheimage.id))
@@ -454,7 +475,7 @@ Found2:
 	bplong(b[19:], uint32(int(c.x)+wid))
 	bplong(b[23:], uint32(bottom))
 	bplong(b[27:], uint32(fi.X))
-	bplong(b[31:], uint32(fi.Top))
+	bplong(b[31:], uint32(sy))
 	b[35] = byte(fi.Left)
 	b[36] = fi.Width
 	d.mu.Unlock()
@@ -462,6 +483,35 @@ Found2:
 	return 1, nil
 }
 
+// AddFallback adds fb to the fonts searched for characters f lacks.
+// Fallbacks are searched in the order they were added, after f's own
+// subfonts and before PJW is drawn for a missing character, so that
+// for example a Latin font can show CJK text from another font. Glyphs
+// from fb are drawn on f's baseline, with any part above f's ascent or
+// below its descent cut off; fb itself is unaffected. The
+// fallbacks of fb at the time of the call are included. Both fonts
+// must be on the same display, and fb must not be freed before f.
+func (f *Font) AddFallback(fb *Font) error {
+	if fb == nil || fb == f {
+		return fmt.Errorf("addfallback: bad font")
+	}
+	if fb.Display != f.Display {
+		return fmt.Errorf("addfallback: fonts on different displays")
+	}
+	for _, cf := range fb.sub[:fb.nsub] {
+		if cf.font == nil {
+			cf.font = fb
+		}
+		f.sub = append(f.sub, cf)
+	}
+	f.nsub = len(f.sub)
+	// Forget characters cached as PJW that fb may now supply.
+	for i := range f.cache {
+		f.cache[i] = Cacheinfo{}
+	}
+	return nil
+}
+
 // fontresize allocates/resizes the font cache image and sends 'i' to devdraw.
 // Port of 9front fontresize(). Returns true if cache pointer unchanged.
 func (f *Font) fontresize(wid, ncache, depth int) bool {
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"strconv"
 	"strings"
@@ -22,6 +23,15 @@ func InitDraw(errfn func(string), fontname, label, windir string) (*Display, err
 	return geninitdraw("/dev", errfn, fontname, label, windir, false)
 }
 
+// GenInitDraw is like InitDraw but opens the draw device and window
+// files under devdir instead of /dev, so that a program can open a
+// second display, such as one imported from another machine and
+// mounted elsewhere. If windir is empty it defaults to devdir.
+// Port of 9front geninitdraw().
+func GenInitDraw(devdir string, errfn func(string), fontname, label, windir string) (*Display, error) {
+	return geninitdraw(devdir, errfn, fontname, label, windir, false)
+}
+
 func geninitdraw(devdir string, errfn func(string), fontname, label, windir string, scalable bool) (*Display, error) {
 	if windir == "" {
 		windir = devdir
@@ -139,22 +149,9 @@ func geninitdraw(devdir string, errfn func(string), fontname, label, windir stri
 	}
 	d.DefaultFont, err = d.OpenFont(fontname)
 	if err != nil {
-		// Try to get the built-in default subfont, then build
-		// a proper font from it — matching the C pattern:
-		//   df = getdefont(display);
-		//   installsubfont("*default*", df);
-		//   snprint(buf, ..., "%d %d\n0 %d\t*default*\n", df->height, df->ascent, df->n-1);
-		//   font = buildfont(display, buf, "*default*");
-		d.DefaultSubfont = d.getdefont()
-		if d.DefaultSubfont != nil {
-			InstallSubfont("*default*", d.DefaultSubfont)
-			desc := fmt.Sprintf("%d %d\n0 %d\t*default*\n",
-				d.DefaultSubfont.Height, d.DefaultSubfont.Ascent,
-				d.DefaultSubfont.N-1)
-			d.DefaultFont, err = d.BuildFont([]byte(desc), "*default*")
-			if err != nil {
-				d.DefaultFont = nil
-			}
+		if err := d.builddefont(); err != nil {
+			d.Close()
+			return nil, fmt.Errorf("initdraw: %v", err)
 		}
 	}
 
@@ -170,8 +167,33 @@ func geninitdraw(devdir string, errfn func(string), fontname, label, windir stri
 	return d, nil
 }
 
+// builddefont makes the built-in font the display's default font.
+// It matches the C pattern:
+//
+//	df = getdefont(display);
+//	installsubfont("*default*", df);
+//	snprint(buf, ..., "%d %d\n0 %d\t*default*\n", df->height, df->ascent, df->n-1);
+//	font = buildfont(display, buf, "*default*");
+func (d *Display) builddefont() error {
+	d.DefaultSubfont = d.getdefont()
+	if d.DefaultSubfont == nil {
+		return errors.New("can't open default subfont")
+	}
+	InstallSubfont("*default*", d.DefaultSubfont)
+	desc := fmt.Sprintf("%d %d\n0 %d\t*default*\n",
+		d.DefaultSubfont.Height, d.DefaultSubfont.Ascent,
+		d.DefaultSubfont.N-1)
+	f, err := d.BuildFont([]byte(desc), "*default*")
+	if err != nil {
+		return fmt.Errorf("can't open default font: %v", err)
+	}
+	d.DefaultFont = f
+	return nil
+}
+
 // Close closes the display connection and frees all resources.
 func (d *Display) Close() error {
+	d.stoponce.Do(func() { close(d.stopchan()) })
 	if d.reffd != nil {
 		d.reffd.Close()
 	}
@@ -184,21 +206,59 @@ func (d *Display) Close() error {
 	return nil
 }
 
-// SetLabel sets the window title.
+// DevDir returns the directory holding the display's devices,
+// usually /dev.
+func (d *Display) DevDir() string {
+	if d.devdir == "" {
+		return "/dev"
+	}
+	return d.devdir
+}
+
+// devpath returns the path of the named device file of the display.
+func (d *Display) devpath(name string) string {
+	return d.DevDir() + "/" + name
+}
+
+// ErrNoLabel is returned by SetLabel when the display has no label
+// file, as on a 9front console not running rio.
+var ErrNoLabel = errors.New("setlabel: no label file")
+
+// SetLabel sets the window title by writing windir/label. It may be
+// called at any time to change the title.
+//
+// Under rio the label file belongs to the program's window and names it
+// in rio's menu of hidden windows. Under drawterm the label file sets the
+// title of the host window. Elsewhere there may be no label file; SetLabel
+// then returns ErrNoLabel, which callers may ignore. In every case the
+// label is remembered and returned by Label.
 func (d *Display) SetLabel(label string) error {
-	// Write label file in windir
-	path := d.windir + "/label"
-	fd, err := os.OpenFile(path, os.O_WRONLY, 0)
+	d.mu.Lock()
+	d.label = label
+	d.mu.Unlock()
+
+	if d.windir == "" {
+		return ErrNoLabel
+	}
+	fd, err := os.OpenFile(d.windir+"/label", os.O_WRONLY, 0)
 	if err != nil {
-		// Try creating it
-		fd, err = os.Create(path)
-		if err != nil {
-			return err
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNoLabel
 		}
+		return fmt.Errorf("setlabel: %v", err)
 	}
 	defer fd.Close()
-	_, err = fd.WriteString(label)
-	return err
+	if _, err := fd.WriteString(label); err != nil {
+		return fmt.Errorf("setlabel: %v", err)
+	}
+	return nil
+}
+
+// Label returns the label most recently set with SetLabel.
+func (d *Display) Label() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.label
 }
 
 // Flush flushes any buffered draw commands to the display.
@@ -254,6 +314,10 @@ func (d *Display) bufimage(n int) ([]byte, error) {
 
 // Attach re-attaches to the display after a resize.
 func (d *Display) Attach(ref int) error {
+	if d.ctlfd == nil {
+		// A memory display is never resized.
+		return nil
+	}
 	// Re-read ctl to get new display dimensions
 	_, err := d.ctlfd.Seek(0, 0)
 	if err != nil {
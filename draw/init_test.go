@@ -1,6 +1,11 @@
 package draw
 
-import "testing"
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestParseCtlLine(t *testing.T) {
 	// Simulated ctl output: 12 fields of 11 chars each + space
@@ -107,3 +112,69 @@ func TestBufimageop(t *testing.T) {
 		t.Errorf("bufimageop DoverS: prefix[1] = %d, want %d", d.buf[11], DoverS)
 	}
 }
+
+func TestDevDir(t *testing.T) {
+	d := &Display{}
+	if got := d.DevDir(); got != "/dev" {
+		t.Errorf("default DevDir = %q, want /dev", got)
+	}
+	d.devdir = "/n/other/dev"
+	if got := d.devpath("cursor"); got != "/n/other/dev/cursor" {
+		t.Errorf("devpath(cursor) = %q", got)
+	}
+}
+
+func TestDevDirDevices(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"cursor", "mouse", "cons", "consctl"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := &Display{devdir: dir}
+
+	if err := d.SetCursor(ArrowCursor); err != nil {
+		t.Fatalf("SetCursor: %v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "cursor")); len(b) != 72 {
+		t.Errorf("cursor file has %d bytes, want 72", len(b))
+	}
+
+	ec, err := d.Einit(Emouse | Ekeyboard)
+	if err != nil {
+		t.Fatalf("Einit: %v", err)
+	}
+	ec.Close()
+	if b, _ := os.ReadFile(filepath.Join(dir, "consctl")); string(b) != "rawon" {
+		t.Errorf("consctl = %q, want rawon", b)
+	}
+}
+
+func TestSetLabel(t *testing.T) {
+	dir := t.TempDir()
+	d := &Display{windir: dir}
+
+	if err := d.SetLabel("acme"); !errors.Is(err, ErrNoLabel) {
+		t.Errorf("SetLabel without label file = %v, want ErrNoLabel", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "label")); err == nil {
+		t.Error("SetLabel created a label file")
+	}
+	if got := d.Label(); got != "acme" {
+		t.Errorf("Label = %q, want acme", got)
+	}
+
+	path := filepath.Join(dir, "label")
+	if err := os.WriteFile(path, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetLabel("acme /usr/glenda"); err != nil {
+		t.Fatalf("SetLabel: %v", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "acme /usr/glenda" {
+		t.Errorf("label file = %q", b)
+	}
+	if got := d.Label(); got != "acme /usr/glenda" {
+		t.Errorf("Label = %q", got)
+	}
+}
@@ -1,6 +1,7 @@
 package draw
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"unicode/utf8"
@@ -85,6 +86,57 @@ const (
 	Kmouse  = Spec | 0x100
 )
 
+// Mod is a set of modifier keys held down.
+type Mod uint
+
+// Modifier bits.
+const (
+	ModShift Mod = 1 << iota
+	ModCtl
+	ModAlt
+	ModAltgr
+	ModMod4
+)
+
+// modkeys maps modifier keys to their bits.
+var modkeys = map[rune]Mod{
+	Kshift: ModShift,
+	Kctl:   ModCtl,
+	Kalt:   ModAlt,
+	Kaltgr: ModAltgr,
+	Kmod4:  ModMod4,
+}
+
+// String returns the held modifiers as, for example, "Ctrl+Shift".
+func (m Mod) String() string {
+	var s string
+	for _, n := range []struct {
+		m    Mod
+		name string
+	}{
+		{ModCtl, "Ctrl"}, {ModAlt, "Alt"}, {ModAltgr, "AltGr"},
+		{ModMod4, "Mod4"}, {ModShift, "Shift"},
+	} {
+		if m&n.m != 0 {
+			if s != "" {
+				s += "+"
+			}
+			s += n.name
+		}
+	}
+	return s
+}
+
+// A KeyEvent reports a key being pressed or released. Key is the key
+// itself, not the character it types: with Shift held, the a key is
+// still 'a'. Modifier keys have events of their own. Mod holds the
+// modifiers down after the event.
+type KeyEvent struct {
+	Key  rune
+	Down bool
+	Mod  Mod
+}
+
 // InitKeyboard opens the keyboard device and returns a Keyboardctl.
 // If file is empty, it defaults to /dev/cons.
 func InitKeyboard(file string) (*Keyboardctl, error) {
@@ -104,31 +156,55 @@ func InitKeyboard(file string) (*Keyboardctl, error) {
 		return nil, fmt.Errorf("initkeyboard: can't open %s: %v", ctlfile, err)
 	}
 
-	kc := &Keyboardctl{
-		C:     make(chan rune, 20),
-		file:  consfd,
-		ctlfd: ctlfd,
-	}
-
-	if err := kc.Ctl("rawon"); err != nil {
+	if _, err := ctlfd.Write([]byte("rawon")); err != nil {
 		ctlfd.Close()
 		consfd.Close()
 		return nil, fmt.Errorf("initkeyboard: can't turn on raw mode: %v", err)
 	}
 
-	go kc.readproc()
+	return newKeyboardctl(consfd, ctlfd), nil
+}
+
+// InitKeyboardContext is like InitKeyboard, but the returned
+// Keyboardctl is closed when ctx is done.
+func InitKeyboardContext(ctx context.Context, file string) (*Keyboardctl, error) {
+	kc, err := InitKeyboard(file)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			kc.Close()
+		case <-kc.done:
+		}
+	}()
 	return kc, nil
 }
 
+// newKeyboardctl wraps an open cons file and starts its reader.
+func newKeyboardctl(consfd, ctlfd *os.File) *Keyboardctl {
+	kc := &Keyboardctl{
+		C:     make(chan rune, 20),
+		file:  consfd,
+		ctlfd: ctlfd,
+		done:  make(chan struct{}),
+	}
+	go kc.readproc(consfd)
+	return kc
+}
+
 // readproc reads keyboard input in a goroutine, decoding UTF-8 runes
-// and sending them on kc.C.
-func (kc *Keyboardctl) readproc() {
+// and sending them on kc.C. When the cons file fails or is closed,
+// kc.C is closed and the done channel is signalled.
+func (kc *Keyboardctl) readproc(file *os.File) {
+	defer kc.shutdown()
+
 	buf := make([]byte, 20)
 	n := 0
 	for {
-		m, err := kc.file.Read(buf[n:])
+		m, err := file.Read(buf[n:])
 		if err != nil || m <= 0 {
-			close(kc.C)
 			return
 		}
 		n += m
@@ -136,11 +212,7 @@ func (kc *Keyboardctl) readproc() {
 			r, size := utf8.DecodeRune(buf[:n])
 			n -= size
 			copy(buf, buf[size:size+n])
-			select {
-			case kc.C <- r:
-			default:
-				// drop if channel full
-			}
+			kc.send(r)
 		}
 	}
 }
@@ -156,14 +228,22 @@ func (kc *Keyboardctl) Ctl(msg string) error {
 	return err
 }
 
-// Close closes the keyboard connection.
+// Close closes the keyboard connection. Closing the cons file unblocks
+// the reader, which then closes C; use Done to wait for it.
+// Close may be called more than once.
 func (kc *Keyboardctl) Close() {
-	if kc.ctlfd != nil {
-		kc.ctlfd.Close()
-		kc.ctlfd = nil
-	}
-	if kc.file != nil {
-		kc.file.Close()
-		kc.file = nil
-	}
+	kc.once.Do(func() {
+		if kc.ctlfd != nil {
+			kc.ctlfd.Close()
+		}
+		if kc.file != nil {
+			kc.file.Close()
+		}
+	})
+}
+
+// Done returns a channel that is closed once the reader has exited
+// and C has been closed.
+func (kc *Keyboardctl) Done() <-chan struct{} {
+	return kc.done
 }
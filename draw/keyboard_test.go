@@ -1,6 +1,11 @@
 package draw
 
-import "testing"
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
 
 // TestKeyboardConstants verifies all key constants match 9front keyboard.h exactly.
 func TestKeyboardConstants(t *testing.T) {
@@ -88,3 +93,48 @@ func TestKdownEqualsKview(t *testing.T) {
 		t.Errorf("Kdown (%#x) != Kview (%#x)", Kdown, Kview)
 	}
 }
+
+// TestKeyboardctlClose verifies that runes are decoded from the cons
+// file and that Close closes C and signals Done.
+func TestKeyboardctlClose(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	kc := newKeyboardctl(r, nil)
+	if _, err := w.Write([]byte("a日")); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []rune{'a', '日'} {
+		select {
+		case c := <-kc.C:
+			if c != want {
+				t.Errorf("got %q, want %q", c, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+
+	kc.Close()
+	kc.Close()
+	select {
+	case <-kc.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done not signalled after Close")
+	}
+	if _, ok := <-kc.C; ok {
+		t.Error("C still open after Close")
+	}
+}
+
+// TestInitKeyboardContextBadFile verifies the error path is unchanged.
+func TestInitKeyboardContextBadFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := InitKeyboardContext(ctx, "/nonexistent/cons"); err == nil {
+		t.Error("InitKeyboardContext on missing file should fail")
+	}
+}
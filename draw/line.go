@@ -2,6 +2,7 @@ package draw
 
 // Line draws a line from p0 to p1 with thickness 1+2*radius.
 // End0 and end1 specify the end styles (Endsquare, Enddisc, or Endarrow).
+// A line that cannot touch dst's clipping rectangle is not sent to devdraw.
 func (dst *Image) Line(p0, p1 Point, end0, end1, radius int, src *Image, sp Point) {
 	dst.LineOp(p0, p1, end0, end1, radius, src, sp, SoverD)
 }
@@ -16,6 +17,12 @@ func (dst *Image) LineOp(p0, p1 Point, end0, end1, radius int, src *Image, sp Po
 	if src == nil {
 		src = d.Black
 	}
+	if r, ok := visclip(dst); ok {
+		m := max(endmargin(end0, radius), endmargin(end1, radius))
+		if r.Empty() || !segvisible(p0, p1, m, r) {
+			return
+		}
+	}
 
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -56,6 +63,8 @@ func addcoord(buf []byte, oldx, newx int) int {
 }
 
 // Poly draws a polygon connecting the points.
+// Segments that cannot touch dst's clipping rectangle are not sent to
+// devdraw; the rest are drawn exactly as they would be otherwise.
 func (dst *Image) Poly(p []Point, end0, end1, radius int, src *Image, sp Point) {
 	dst.PolyOp(p, end0, end1, radius, src, sp, SoverD)
 }
@@ -74,6 +83,29 @@ func (dst *Image) PolyOp(p []Point, end0, end1, radius int, src *Image, sp Point
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	last := len(p) - 1
+	if r, ok := visclip(dst); ok && last > 0 {
+		if r.Empty() {
+			return
+		}
+		// devdraw draws each segment separately, joined by discs, with
+		// sp offset by the segment's start from p[0]; drawing the
+		// visible runs the same way gives the same pixels.
+		runs := polyruns(p, end0, end1, radius, r)
+		if len(runs) != 1 || runs[0] != [2]int{0, last} {
+			for _, run := range runs {
+				e0, e1 := Enddisc, Enddisc
+				if run[0] == 0 {
+					e0 = end0
+				}
+				if run[1] == last {
+					e1 = end1
+				}
+				d.dopoly('p', dst, p[run[0]:run[1]+1], e0, e1, radius, src, sp.Add(p[run[0]].Sub(p[0])), op)
+			}
+			return
+		}
+	}
 	d.dopoly('p', dst, p, end0, end1, radius, src, sp, op)
 }
 
@@ -0,0 +1,621 @@
+package draw
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// InitMemory returns a Display that draws into memory instead of on a
+// devdraw device, for tests and for rendering without a window system.
+// Its screen image has rectangle r and channel format pix and starts out
+// white. Drawing happens when buffered messages are flushed, as on a
+// real display; afterwards the pixels can be read with Unload or
+// Capture, written as PNG with WritePNG, or examined through MemImage.
+//
+// The default font is the built-in one, so that what is drawn does not
+// depend on the fonts installed. Windows allocated on a memory display
+// draw straight into their screen's pixels: they do not obscure one
+// another and keep no backing store. There is no mouse, keyboard or
+// window label, and the snarf buffer is private to the display.
+func InitMemory(r Rectangle, pix Pix) (*Display, error) {
+	if Badrect(r) || r.Empty() {
+		return nil, fmt.Errorf("initmemory: bad rectangle")
+	}
+	if chantodepth(pix) == 0 {
+		return nil, fmt.Errorf("initmemory: bad channel descriptor")
+	}
+	d := &Display{
+		bufsize: drawBufSize,
+		datafd:  newmemdev(pix, r),
+		Snarf:   new(MemSnarf),
+	}
+	d.buf = make([]byte, d.bufsize+5)
+	d.Image = &Image{
+		Display: d,
+		id:      0,
+		Pix:     pix,
+		Depth:   chantodepth(pix),
+		R:       r,
+		Clipr:   r,
+	}
+	d.ScreenImage = d.Image
+
+	var err error
+	d.White, err = d.AllocImage(Rect(0, 0, 1, 1), GREY1, true, DWhite)
+	if err != nil {
+		return nil, fmt.Errorf("initmemory: alloc white: %v", err)
+	}
+	d.Black, err = d.AllocImage(Rect(0, 0, 1, 1), GREY1, true, DBlack)
+	if err != nil {
+		return nil, fmt.Errorf("initmemory: alloc black: %v", err)
+	}
+	d.Opaque = d.White
+	d.Transparent = d.Black
+	if err := d.builddefont(); err != nil {
+		return nil, fmt.Errorf("initmemory: %v", err)
+	}
+	if err := d.Flush(); err != nil {
+		return nil, fmt.Errorf("initmemory: %v", err)
+	}
+	return d, nil
+}
+
+// MemImage returns the pixels of a memory display's screen image, or
+// nil if d was not made by InitMemory. They show what has been flushed,
+// and must not be read while other goroutines draw on d.
+func (d *Display) MemImage() *image.RGBA {
+	md, ok := d.datafd.(*memdev)
+	if !ok {
+		return nil
+	}
+	return md.images[0].m
+}
+
+// memdev stands in for a devdraw connection's data file: writes carry
+// draw messages, which it carries out on images held in memory, and a
+// read returns the pixels asked for by the last 'r' message.
+type memdev struct {
+	images  map[int]*memimage
+	screens map[int]*memscreen
+	names   map[string]*memimage
+	rdata   []byte
+}
+
+func newmemdev(pix Pix, r Rectangle) *memdev {
+	return &memdev{
+		images:  map[int]*memimage{0: newmemimage(pix, r, r, false, DWhite)},
+		screens: make(map[int]*memscreen),
+		names:   make(map[string]*memimage),
+	}
+}
+
+var errShortMsg = errors.New("memdraw: short message")
+
+// Write carries out the draw messages in p. As with devdraw, a bad
+// message stops the rest of the write and is reported as an error.
+func (md *memdev) Write(p []byte) (int, error) {
+	op := SoverD
+	for a := p; len(a) > 0; {
+		n, err := md.msg(a, op)
+		if err != nil {
+			return 0, err
+		}
+		op = SoverD
+		if a[0] == 'O' {
+			op = Op(a[1])
+		}
+		a = a[n:]
+	}
+	return len(p), nil
+}
+
+// Read returns the pixel data requested by the last 'r' message.
+func (md *memdev) Read(p []byte) (int, error) {
+	if md.rdata == nil {
+		return 0, errors.New("memdraw: no read pending")
+	}
+	n := copy(p, md.rdata)
+	md.rdata = nil
+	return n, nil
+}
+
+func (md *memdev) Close() error {
+	return nil
+}
+
+// image returns the image with the id at a[0:4].
+func (md *memdev) image(a []byte) (*memimage, error) {
+	id := int(glong(a))
+	i, ok := md.images[id]
+	if !ok {
+		return nil, fmt.Errorf("memdraw: unknown image id %d", id)
+	}
+	return i, nil
+}
+
+// rect decodes the rectangle at a[0:16].
+func rect(a []byte) Rectangle {
+	return Rect(int(int32(glong(a))), int(int32(glong(a[4:]))), int(int32(glong(a[8:]))), int(int32(glong(a[12:]))))
+}
+
+// point decodes the point at a[0:8].
+func point(a []byte) Point {
+	return Pt(int(int32(glong(a))), int(int32(glong(a[4:]))))
+}
+
+// msg carries out the message at the start of a, drawing with op, and
+// returns its length.
+func (md *memdev) msg(a []byte, op Op) (int, error) {
+	need := func(n int) error {
+		if len(a) < n {
+			return errShortMsg
+		}
+		return nil
+	}
+	switch a[0] {
+	case 'b': // alloc: 'b' id[4] screenid[4] refresh[1] chan[4] repl[1] r[4*4] clipr[4*4] color[4]
+		if err := need(51); err != nil {
+			return 0, err
+		}
+		id := int(glong(a[1:]))
+		if _, ok := md.images[id]; ok {
+			return 0, fmt.Errorf("memdraw: image id %d in use", id)
+		}
+		pix := Pix(glong(a[10:]))
+		r, clipr := rect(a[15:]), rect(a[31:])
+		if chantodepth(pix) == 0 || Badrect(r) {
+			return 0, errors.New("memdraw: bad image")
+		}
+		if sid := int(glong(a[5:])); sid != 0 {
+			s, ok := md.screens[sid]
+			if !ok {
+				return 0, fmt.Errorf("memdraw: unknown screen id %d", sid)
+			}
+			w := &memimage{pix: pix, r: r, clipr: r, m: s.image.m, delta: s.image.delta, scr: s}
+			w.setpix()
+			w.fill(r, glong(a[47:]))
+			md.images[id] = w
+			return 51, nil
+		}
+		if int64(r.Dx())*int64(r.Dy()) > 1<<28 {
+			return 0, errors.New("memdraw: image too large")
+		}
+		md.images[id] = newmemimage(pix, r, clipr, a[14] != 0, glong(a[47:]))
+		return 51, nil
+
+	case 'f': // free: 'f' id[4]
+		if err := need(5); err != nil {
+			return 0, err
+		}
+		i, err := md.image(a[1:])
+		if err != nil {
+			return 0, err
+		}
+		if i.scr != nil {
+			// Uncover the window's place on the screen.
+			s := i.scr
+			sr := i.r.Add(i.delta).Sub(s.image.delta)
+			memdraw(s.image, sr, s.fill, sr.Min, nil, ZP, S)
+		}
+		delete(md.images, int(glong(a[1:])))
+		return 5, nil
+
+	case 'A': // alloc screen: 'A' id[4] imageid[4] fillid[4] public[1]
+		if err := need(14); err != nil {
+			return 0, err
+		}
+		i, err := md.image(a[5:])
+		if err != nil {
+			return 0, err
+		}
+		fill, err := md.image(a[9:])
+		if err != nil {
+			return 0, err
+		}
+		md.screens[int(glong(a[1:]))] = &memscreen{image: i, fill: fill, public: a[13] != 0}
+		return 14, nil
+
+	case 'F': // free screen: 'F' id[4]
+		if err := need(5); err != nil {
+			return 0, err
+		}
+		delete(md.screens, int(glong(a[1:])))
+		return 5, nil
+
+	case 'S': // public screen: 'S' id[4] chan[4]
+		if err := need(9); err != nil {
+			return 0, err
+		}
+		if s, ok := md.screens[int(glong(a[1:]))]; !ok || !s.public {
+			return 0, fmt.Errorf("memdraw: no public screen %d", glong(a[1:]))
+		}
+		return 9, nil
+
+	case 'c': // clip: 'c' id[4] repl[1] clipr[4*4]
+		if err := need(22); err != nil {
+			return 0, err
+		}
+		i, err := md.image(a[1:])
+		if err != nil {
+			return 0, err
+		}
+		i.repl = a[5] != 0
+		i.clipr = rect(a[6:])
+		return 22, nil
+
+	case 'd': // draw: 'd' dstid[4] srcid[4] maskid[4] r[4*4] sp[2*4] mp[2*4]
+		if err := need(45); err != nil {
+			return 0, err
+		}
+		dst, src, mask, err := md.images3(a[1:], a[5:], a[9:])
+		if err != nil {
+			return 0, err
+		}
+		memdraw(dst, rect(a[13:]), src, point(a[29:]), mask, point(a[37:]), op)
+		return 45, nil
+
+	case 'L': // line: 'L' dstid[4] p0[2*4] p1[2*4] end0[4] end1[4] radius[4] srcid[4] sp[2*4]
+		if err := need(45); err != nil {
+			return 0, err
+		}
+		dst, err := md.image(a[1:])
+		if err != nil {
+			return 0, err
+		}
+		src, err := md.image(a[33:])
+		if err != nil {
+			return 0, err
+		}
+		memline(dst, point(a[5:]), point(a[13:]), int(int32(glong(a[21:]))), int(int32(glong(a[25:]))),
+			int(int32(glong(a[29:]))), src, point(a[37:]), op)
+		return 45, nil
+
+	case 'p', 'P': // poly: 'p' dstid[4] n[2] end0[4] end1[4] radius[4] srcid[4] sp[2*4] dp[2*2*(n+1)]
+		if err := need(31); err != nil {
+			return 0, err
+		}
+		dst, err := md.image(a[1:])
+		if err != nil {
+			return 0, err
+		}
+		src, err := md.image(a[19:])
+		if err != nil {
+			return 0, err
+		}
+		np := int(gshort(a[5:])) + 1
+		pts := make([]Point, np)
+		m := 31
+		var o Point
+		for i := range pts {
+			var err error
+			if m, o.X, err = getcoord(a, m, o.X); err != nil {
+				return 0, err
+			}
+			if m, o.Y, err = getcoord(a, m, o.Y); err != nil {
+				return 0, err
+			}
+			pts[i] = o
+		}
+		end0, end1 := int(int32(glong(a[7:]))), int(int32(glong(a[11:])))
+		if a[0] == 'p' {
+			mempoly(dst, pts, end0, end1, int(int32(glong(a[15:]))), src, point(a[23:]), op)
+		} else {
+			memfillpoly(dst, pts, end0, src, point(a[23:]), op)
+		}
+		return m, nil
+
+	case 'e', 'E': // ellipse: 'e' dstid[4] srcid[4] center[2*4] a[4] b[4] thick[4] sp[2*4] alpha[4] phi[4]
+		if err := need(45); err != nil {
+			return 0, err
+		}
+		dst, err := md.image(a[1:])
+		if err != nil {
+			return 0, err
+		}
+		src, err := md.image(a[5:])
+		if err != nil {
+			return 0, err
+		}
+		thick := int(int32(glong(a[25:])))
+		if a[0] == 'E' {
+			thick = -1
+		}
+		alpha := glong(a[37:])
+		memellipse(dst, point(a[9:]), int(int32(glong(a[17:]))), int(int32(glong(a[21:]))), thick,
+			src, point(a[29:]), alpha&(1<<31) != 0, int(alpha&^(1<<31)), int(int32(glong(a[41:]))), op)
+		return 45, nil
+
+	case 'i': // font cache: 'i' fontid[4] nchars[4] ascent[1]
+		if err := need(10); err != nil {
+			return 0, err
+		}
+		i, err := md.image(a[1:])
+		if err != nil {
+			return 0, err
+		}
+		// Glyphs are indexed by 2-byte numbers in 'l' and 's'.
+		n := glong(a[5:])
+		if n == 0 || n > 1<<16 {
+			return 0, fmt.Errorf("memdraw: bad font cache size %d", n)
+		}
+		i.fchar = make([]memfchar, n)
+		i.ascent = int(a[9])
+		return 10, nil
+
+	case 'l': // load glyph: 'l' fontid[4] srcid[4] index[2] r[4*4] sp[2*4] left[1] width[1]
+		if err := need(37); err != nil {
+			return 0, err
+		}
+		font, err := md.image(a[1:])
+		if err != nil {
+			return 0, err
+		}
+		src, err := md.image(a[5:])
+		if err != nil {
+			return 0, err
+		}
+		ci := int(gshort(a[9:]))
+		if ci >= len(font.fchar) {
+			return 0, fmt.Errorf("memdraw: glyph index %d out of range", ci)
+		}
+		r := rect(a[11:])
+		memdraw(font, r, src, point(a[27:]), nil, ZP, S)
+		font.fchar[ci] = memfchar{r: r, left: int(int8(a[35])), width: int(a[36])}
+		return 37, nil
+
+	case 's', 'x': // string: 's' dstid[4] srcid[4] fontid[4] p[2*4] clipr[4*4] sp[2*4] ni[2] index[2*ni]
+		// 'x' adds bgid[4] bp[2*4] before the indices.
+		if err := need(47); err != nil {
+			return 0, err
+		}
+		dst, src, font, err := md.images3(a[1:], a[5:], a[9:])
+		if err != nil {
+			return 0, err
+		}
+		ni := int(gshort(a[45:]))
+		m := 47
+		var bg *memimage
+		var bp Point
+		if a[0] == 'x' {
+			if err := need(59); err != nil {
+				return 0, err
+			}
+			if bg, err = md.image(a[47:]); err != nil {
+				return 0, err
+			}
+			bp = point(a[51:])
+			m = 59
+		}
+		if err := need(m + 2*ni); err != nil {
+			return 0, err
+		}
+		idx := make([]int, ni)
+		for k := range idx {
+			idx[k] = int(gshort(a[m+2*k:]))
+			if idx[k] >= len(font.fchar) {
+				return 0, fmt.Errorf("memdraw: glyph index %d out of range", idx[k])
+			}
+		}
+		md.string(dst, src, font, point(a[13:]), rect(a[21:]), point(a[37:]), bg, bp, idx, op)
+		return m + 2*ni, nil
+
+	case 'y': // load: 'y' id[4] r[4*4] data[x*1]
+		if err := need(21); err != nil {
+			return 0, err
+		}
+		i, err := md.image(a[1:])
+		if err != nil {
+			return 0, err
+		}
+		r := rect(a[5:])
+		if !r.In(i.bounds()) || r.Empty() {
+			return 0, errors.New("memdraw: bad load rectangle")
+		}
+		n := 21 + bytesPerLine(r, chantodepth(i.pix))*r.Dy()
+		if err := need(n); err != nil {
+			return 0, err
+		}
+		i.load(r, a[21:n])
+		return n, nil
+
+	case 'Y': // compressed load: 'Y' id[4] r[4*4] data[x*1]
+		if err := need(21); err != nil {
+			return 0, err
+		}
+		i, err := md.image(a[1:])
+		if err != nil {
+			return 0, err
+		}
+		r := rect(a[5:])
+		if !r.In(i.bounds()) || r.Empty() {
+			return 0, errors.New("memdraw: bad load rectangle")
+		}
+		n, err := i.cload(r, a[21:])
+		if err != nil {
+			return 0, err
+		}
+		return 21 + n, nil
+
+	case 'r': // read: 'r' id[4] r[4*4]
+		if err := need(21); err != nil {
+			return 0, err
+		}
+		i, err := md.image(a[1:])
+		if err != nil {
+			return 0, err
+		}
+		r := rect(a[5:])
+		if !r.In(i.bounds()) || r.Empty() {
+			return 0, errors.New("memdraw: bad read rectangle")
+		}
+		md.rdata = i.unload(r)
+		return 21, nil
+
+	case 'N': // name: 'N' id[4] in[1] j[1] name[j]
+		if err := need(7); err != nil {
+			return 0, err
+		}
+		n := 7 + int(a[6])
+		if err := need(n); err != nil {
+			return 0, err
+		}
+		i, err := md.image(a[1:])
+		if err != nil {
+			return 0, err
+		}
+		name := string(a[7:n])
+		if a[5] != 0 {
+			if _, ok := md.names[name]; ok {
+				return 0, fmt.Errorf("memdraw: name %q in use", name)
+			}
+			md.names[name] = i
+		} else if md.names[name] == i {
+			delete(md.names, name)
+		}
+		return n, nil
+
+	case 'n': // attach to named image: 'n' id[4] j[1] name[j]
+		if err := need(6); err != nil {
+			return 0, err
+		}
+		n := 6 + int(a[5])
+		if err := need(n); err != nil {
+			return 0, err
+		}
+		i, ok := md.names[string(a[6:n])]
+		if !ok {
+			return 0, fmt.Errorf("memdraw: no image named %q", a[6:n])
+		}
+		md.images[int(glong(a[1:]))] = i
+		return n, nil
+
+	case 'o': // origin: 'o' id[4] log[2*4] scr[2*4]
+		if err := need(21); err != nil {
+			return 0, err
+		}
+		i, err := md.image(a[1:])
+		if err != nil {
+			return 0, err
+		}
+		md.origin(i, point(a[5:]), point(a[13:]))
+		return 21, nil
+
+	case 't': // top or bottom: 't' top[1] nw[2] n*id[4]
+		if err := need(4); err != nil {
+			return 0, err
+		}
+		n := 4 + 4*int(gshort(a[2:]))
+		if err := need(n); err != nil {
+			return 0, err
+		}
+		// Windows do not obscure one another, so the order is moot.
+		for k := 4; k < n; k += 4 {
+			if _, err := md.image(a[k:]); err != nil {
+				return 0, err
+			}
+		}
+		return n, nil
+
+	case 'O': // set op for the next message: 'O' op[1]
+		if err := need(2); err != nil {
+			return 0, err
+		}
+		return 2, nil
+
+	case 'v': // flush
+		return 1, nil
+	}
+	return 0, fmt.Errorf("memdraw: unknown message %q", a[0])
+}
+
+// images3 returns the three images whose ids are at a, b and c.
+func (md *memdev) images3(a, b, c []byte) (*memimage, *memimage, *memimage, error) {
+	i, err := md.image(a)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	j, err := md.image(b)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	k, err := md.image(c)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return i, j, k, nil
+}
+
+// getcoord decodes a coordinate compressed by addcoord at a[m:],
+// relative to old, and returns the offset following it.
+func getcoord(a []byte, m, old int) (int, int, error) {
+	if m >= len(a) {
+		return 0, 0, errShortMsg
+	}
+	b := a[m]
+	if b&0x80 == 0 {
+		x := int(b & 0x7F)
+		if x&0x40 != 0 {
+			x |= ^0x7F
+		}
+		return m + 1, old + x, nil
+	}
+	if m+3 > len(a) {
+		return 0, 0, errShortMsg
+	}
+	x := int(b&0x7F) | int(a[m+1])<<7 | int(a[m+2])<<15
+	if x&(1<<22) != 0 {
+		x |= ^0 << 23
+	}
+	return m + 3, x, nil
+}
+
+// string draws the glyphs idx of a font cache on dst at baseline
+// point p, clipped to clipr, with src aligned so that sp is at p less
+// the font's ascent. If bg is set the glyph cells are first filled from
+// it, aligned so that bp is at the same point.
+// Port of the 's' and 'x' messages of 9front devdraw.
+func (md *memdev) string(dst, src, font *memimage, p Point, clipr Rectangle, sp Point, bg *memimage, bp Point, idx []int, op Op) {
+	oclipr := dst.clipr
+	defer func() { dst.clipr = oclipr }()
+	dst.clipr = clipr
+
+	if bg != nil {
+		r := Rect(p.X, p.Y-font.ascent, p.X, p.Y-font.ascent+font.r.Dy())
+		for _, ci := range idx {
+			r.Max.X += font.fchar[ci].width
+		}
+		memdraw(dst, r, bg, bp, nil, ZP, op)
+	}
+	for _, ci := range idx {
+		fc := font.fchar[ci]
+		r := Rect(p.X+fc.left, p.Y-font.ascent+fc.r.Min.Y, p.X+fc.left+fc.r.Dx(), p.Y-font.ascent+fc.r.Max.Y)
+		memdraw(dst, r, src, Pt(sp.X+fc.left, sp.Y+fc.r.Min.Y), font, fc.r.Min, op)
+		p.X += fc.width
+		sp.X += fc.width
+	}
+}
+
+// origin moves window i to screen point scr and gives its rectangle
+// the minimum point log, uncovering its old place on the screen.
+func (md *memdev) origin(i *memimage, log, scr Point) {
+	if i.scr == nil {
+		// Not a window: only the coordinates change.
+		d := log.Sub(i.r.Min)
+		i.r = i.r.Add(d)
+		i.clipr = i.clipr.Add(d)
+		i.delta = i.delta.Sub(d)
+		return
+	}
+	s := i.scr
+	old := newmemimage(i.pix, i.r, i.r, false, DTransparent)
+	memdraw(old, i.r, i, i.r.Min, nil, ZP, S)
+	sr := i.r.Add(i.delta).Sub(s.image.delta)
+	memdraw(s.image, sr, s.fill, sr.Min, nil, ZP, S)
+
+	d := log.Sub(i.r.Min)
+	i.r = i.r.Add(d)
+	i.clipr = i.clipr.Add(d)
+	i.delta = scr.Add(s.image.delta).Sub(log)
+	memdraw(i, i.r, old, old.r.Min, nil, ZP, S)
+}
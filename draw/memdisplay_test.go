@@ -0,0 +1,356 @@
+package draw
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+func memDisplay(t *testing.T, pix Pix) *Display {
+	t.Helper()
+	d, err := InitMemory(Rect(0, 0, 100, 80), pix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// memcolor returns the 0xRRGGBBAA color of the screen pixel at (x, y).
+func memcolor(d *Display, x, y int) uint32 {
+	m := d.MemImage()
+	o := m.PixOffset(x, y)
+	return uint32(m.Pix[o])<<24 | uint32(m.Pix[o+1])<<16 | uint32(m.Pix[o+2])<<8 | uint32(m.Pix[o+3])
+}
+
+// memcount returns how many screen pixels in r have color c.
+func memcount(d *Display, r Rectangle, c uint32) int {
+	n := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if memcolor(d, x, y) == c {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestInitMemory(t *testing.T) {
+	d := memDisplay(t, XRGB32)
+	if d.MemImage().Bounds().Dx() != 100 || d.MemImage().Bounds().Dy() != 80 {
+		t.Fatalf("bounds %v", d.MemImage().Bounds())
+	}
+	if n := memcount(d, d.Image.R, DWhite); n != 100*80 {
+		t.Errorf("%d white pixels, want all", n)
+	}
+	if d.DefaultFont == nil || d.ScreenImage != d.Image {
+		t.Error("display not set up")
+	}
+	if _, err := d.Namedimage("x"); err == nil {
+		t.Error("Namedimage succeeded on memory display")
+	}
+	if err := d.SetLabel("x"); err != ErrNoLabel {
+		t.Errorf("SetLabel = %v, want ErrNoLabel", err)
+	}
+	if _, err := InitMemory(Rect(0, 0, 0, 10), RGB24); err == nil {
+		t.Error("InitMemory accepted empty rectangle")
+	}
+	if (&Display{}).MemImage() != nil {
+		t.Error("MemImage of ordinary display not nil")
+	}
+}
+
+func TestMemDraw(t *testing.T) {
+	d := memDisplay(t, XRGB32)
+	red, _ := d.AllocImage(Rect(0, 0, 1, 1), RGB24, true, DRed)
+	half, _ := d.AllocImage(Rect(0, 0, 1, 1), RGBA32, true, 0x00007F7F) // premultiplied half blue
+	clear, _ := d.AllocImage(Rect(0, 0, 1, 1), RGBA32, true, DTransparent)
+	d.Image.Draw(Rect(10, 10, 20, 20), red, ZP)
+	d.Image.Draw(Rect(15, 0, 25, 5), half, ZP)
+	d.Image.DrawOp(Rect(30, 30, 40, 40), clear, nil, ZP, S)
+	d.Flush()
+
+	if n := memcount(d, d.Image.R, DRed); n != 100 {
+		t.Errorf("%d red pixels, want 100", n)
+	}
+	if c := memcolor(d, 15, 0); c != 0x8080FFFF {
+		t.Errorf("half blue over white = %#x, want 0x8080FFFF", c)
+	}
+	// XRGB32 has no alpha, so S with a clear source leaves opaque black.
+	if c := memcolor(d, 35, 35); c != 0x000000FF {
+		t.Errorf("clear on XRGB32 = %#x, want opaque black", c)
+	}
+
+	// With an alpha channel the pixels become clear.
+	a, _ := d.AllocImage(Rect(0, 0, 4, 4), RGBA32, false, DWhite)
+	a.DrawOp(a.R, clear, nil, ZP, S)
+	m, err := a.Capture(a.R)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range m.Pix {
+		if v != 0 {
+			t.Fatalf("cleared RGBA32 image holds %v", m.Pix[:4])
+		}
+	}
+}
+
+func TestMemDrawMaskAndRepl(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	// A 2x2 checkerboard tile replicated over an 8x8 square.
+	tile, _ := d.AllocImage(Rect(0, 0, 2, 2), GREY1, true, DWhite)
+	tile.Load(tile.R, []byte{0x80, 0x40})
+	d.Image.Draw(Rect(0, 0, 8, 8), d.Black, ZP)
+	d.Image.GenDraw(Rect(0, 0, 8, 8), d.White, ZP, tile, ZP)
+	d.Flush()
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			want := uint32(DBlack)
+			if (x+y)%2 == 0 {
+				want = DWhite
+			}
+			if c := memcolor(d, x, y); c != want {
+				t.Fatalf("pixel (%d,%d) = %#x, want %#x", x, y, c, want)
+			}
+		}
+	}
+}
+
+func TestMemDrawScroll(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	for y := 0; y < 10; y++ {
+		c, _ := d.AllocImage(Rect(0, 0, 1, 1), RGB24, true, uint32(y*20)<<24|0xFF)
+		d.Image.Draw(Rect(0, y, 10, y+1), c, ZP)
+	}
+	// Scroll the stripes up and then down by three lines within the screen.
+	d.Image.Draw(Rect(0, 0, 10, 7), d.Image, Pt(0, 3))
+	d.Image.Draw(Rect(0, 13, 10, 20), d.Image, Pt(0, 10))
+	d.Image.Draw(Rect(0, 3, 10, 10), d.Image, Pt(0, 0))
+	d.Flush()
+	for y := 3; y < 10; y++ {
+		if c, want := memcolor(d, 5, y), uint32(y*20)<<24|0xFF; c != want {
+			t.Errorf("row %d = %#x, want %#x", y, c, want)
+		}
+	}
+}
+
+func TestMemLoadUnload(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	for _, pix := range []Pix{GREY1, GREY2, GREY8, CMAP8, RGB16, RGB24, RGBA32, XRGB32} {
+		r := Rect(3, 1, 14, 4)
+		i, err := d.AllocImage(r, pix, false, DWhite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := bytesPerLine(r, i.Depth) * r.Dy()
+		data := make([]byte, n)
+		for k := range data {
+			data[k] = byte(k * 37)
+		}
+		want, err := unpackRGBA(pix, r, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := i.Load(r, data); err != nil {
+			t.Fatal(err)
+		}
+		got, err := i.Capture(r)
+		if err != nil {
+			t.Fatalf("%s: %v", chantostr(pix), err)
+		}
+		if !bytes.Equal(got.Pix, want.Pix) {
+			t.Errorf("%s: round trip changed pixels", chantostr(pix))
+		}
+	}
+}
+
+func TestMemLine(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	d.Image.Line(Pt(10, 10), Pt(20, 10), Endsquare, Endsquare, 0, d.Black, ZP)
+	d.Flush()
+	if n := memcount(d, d.Image.R, DBlack); n != 11 {
+		t.Errorf("thin line has %d pixels, want 11", n)
+	}
+	if n := memcount(d, Rect(10, 10, 21, 11), DBlack); n != 11 {
+		t.Errorf("thin line has %d pixels on its row, want 11", n)
+	}
+
+	d = memDisplay(t, RGB24)
+	d.Image.Line(Pt(10, 10), Pt(30, 10), Enddisc, Endsquare, 2, d.Black, ZP)
+	d.Flush()
+	if n := memcount(d, Rect(10, 8, 31, 13), DBlack); n != 21*5 {
+		t.Errorf("thick line shaft has %d pixels, want %d", n, 21*5)
+	}
+	if memcolor(d, 8, 10) != DBlack || memcolor(d, 7, 10) != DWhite || memcolor(d, 31, 10) != DWhite {
+		t.Error("disc end wrong")
+	}
+
+	d = memDisplay(t, RGB24)
+	d.Image.Line(Pt(10, 40), Pt(50, 40), Endsquare, Endarrow, 0, d.Black, ZP)
+	d.Flush()
+	if memcolor(d, 50, 40) != DBlack || memcolor(d, 51, 40) != DWhite {
+		t.Error("arrow tip not at end point")
+	}
+	if memcount(d, Rect(40, 36, 50, 45), DBlack) < 20 {
+		t.Error("arrow head missing")
+	}
+}
+
+func TestMemPoly(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	d.Image.FillPoly([]Point{{10, 10}, {20, 10}, {20, 20}, {10, 20}}, ^0, d.Black, ZP)
+	d.Flush()
+	if n := memcount(d, d.Image.R, DBlack); n != 100 {
+		t.Errorf("filled square has %d pixels, want 100", n)
+	}
+
+	// A pentagram: its middle is filled by the non-zero rule only.
+	star := []Point{{50, 10}, {62, 46}, {31, 24}, {69, 24}, {38, 46}}
+	d = memDisplay(t, RGB24)
+	d.Image.FillPoly(star, ^0, d.Black, ZP)
+	d.Flush()
+	if memcolor(d, 50, 30) != DBlack {
+		t.Error("non-zero fill left the middle empty")
+	}
+	d = memDisplay(t, RGB24)
+	d.Image.FillPoly(star, 1, d.Black, ZP)
+	d.Flush()
+	if memcolor(d, 50, 30) != DWhite {
+		t.Error("even-odd fill filled the middle")
+	}
+
+	d = memDisplay(t, RGB24)
+	d.Image.Poly([]Point{{10, 10}, {30, 10}, {30, 30}}, Endsquare, Endsquare, 0, d.Black, ZP)
+	d.Flush()
+	if n := memcount(d, d.Image.R, DBlack); n != 41 {
+		t.Errorf("polyline has %d pixels, want 41", n)
+	}
+}
+
+func TestMemEllipse(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	d.Image.FillEllipse(Pt(50, 40), 10, 5, d.Black, ZP)
+	d.Flush()
+	for _, tt := range []struct {
+		x, y int
+		in   bool
+	}{
+		{50, 40, true}, {60, 40, true}, {61, 40, false}, {40, 40, true}, {39, 40, false},
+		{50, 45, true}, {50, 46, false}, {50, 35, true}, {50, 34, false},
+	} {
+		if in := memcolor(d, tt.x, tt.y) == DBlack; in != tt.in {
+			t.Errorf("filled ellipse at (%d,%d): %v, want %v", tt.x, tt.y, in, tt.in)
+		}
+	}
+
+	d = memDisplay(t, RGB24)
+	d.Image.Ellipse(Pt(50, 40), 20, 20, 0, d.Black, ZP)
+	d.Image.FillArc(Pt(20, 20), 10, 10, d.Black, ZP, 0, 90)
+	d.Flush()
+	if memcolor(d, 50, 40) != DWhite || memcolor(d, 70, 40) != DBlack || memcolor(d, 50, 20) != DBlack {
+		t.Error("ellipse outline wrong")
+	}
+	// The quarter from 3 o'clock to 12 o'clock is above and right of center.
+	if memcolor(d, 25, 15) != DBlack || memcolor(d, 15, 15) != DWhite || memcolor(d, 25, 25) != DWhite {
+		t.Error("filled arc wrong")
+	}
+}
+
+func TestMemString(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	f := d.DefaultFont
+	p := d.Image.String(Pt(10, 10), d.Black, ZP, f, "HI")
+	d.Flush()
+	if p.X != 10+f.StringWidth("HI") {
+		t.Errorf("String returned %v", p)
+	}
+	cell := Rect(10, 10, p.X, 10+f.Height)
+	n := memcount(d, cell, DBlack)
+	if n == 0 {
+		t.Fatal("String drew nothing")
+	}
+	if m := memcount(d, d.Image.R, DBlack); m != n {
+		t.Errorf("String drew %d pixels outside its cell", m-n)
+	}
+
+	// StringBg fills the cells behind the text.
+	red, _ := d.AllocImage(Rect(0, 0, 1, 1), RGB24, true, DRed)
+	p = d.Image.StringBg(Pt(10, 40), d.Black, ZP, f, "HI", red, ZP)
+	d.Flush()
+	cell = Rect(10, 40, p.X, 40+f.Height)
+	if memcount(d, cell, DRed)+memcount(d, cell, DBlack) != cell.Dx()*cell.Dy() {
+		t.Error("StringBg left cells unfilled")
+	}
+}
+
+func TestMemWindow(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	grey, _ := d.AllocImage(Rect(0, 0, 1, 1), RGB24, true, DPaleyellow)
+	s, err := d.AllocScreen(d.Image, grey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := s.AllocWindow(Rect(10, 10, 30, 30), Refnone, DRed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Draw(Rect(0, 0, 100, 100), d.Black, ZP)
+	d.Flush()
+	if n := memcount(d, d.Image.R, DBlack); n != 400 {
+		t.Errorf("window drew %d pixels, want 400", n)
+	}
+	w.Free()
+	d.Flush()
+	if n := memcount(d, d.Image.R, DPaleyellow); n != 400 {
+		t.Errorf("freed window left %d fill pixels, want 400", n)
+	}
+}
+
+func TestMemWritePNG(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	d.Image.Draw(Rect(0, 0, 5, 5), d.Black, ZP)
+	d.Flush()
+	var buf bytes.Buffer
+	if err := d.WritePNG(&buf, d.Image.R); err != nil {
+		t.Fatal(err)
+	}
+	m, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := m.At(0, 0).RGBA(); r != 0 {
+		t.Error("PNG pixel (0,0) not black")
+	}
+	if r, _, _, _ := m.At(50, 50).RGBA(); r != 0xFFFF {
+		t.Error("PNG pixel (50,50) not white")
+	}
+}
+
+func TestMemBadMessage(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	i := &Image{Display: d, id: 999, R: Rect(0, 0, 1, 1), Clipr: Rect(0, 0, 1, 1)}
+	d.Image.Draw(d.Image.R, i, ZP)
+	if err := d.Flush(); err == nil {
+		t.Error("draw from unknown image id succeeded")
+	}
+}
+
+func TestMemBadFontCache(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	for _, n := range []uint32{0, 1<<16 + 1, 0xFFFFFFFF} {
+		d.mu.Lock()
+		a, err := d.bufimage(10)
+		if err != nil {
+			d.mu.Unlock()
+			t.Fatal(err)
+		}
+		a[0] = 'i'
+		bplong(a[1:], uint32(d.Image.id))
+		bplong(a[5:], n)
+		a[9] = 10
+		d.mu.Unlock()
+		if err := d.Flush(); err == nil {
+			t.Errorf("font cache of %d glyphs accepted", n)
+		}
+	}
+}
@@ -0,0 +1,693 @@
+package draw
+
+import (
+	"errors"
+	"image"
+	"math"
+	"sort"
+)
+
+// memimage is an image held by a memory display (see memdisplay.go).
+// Its pixels are kept premultiplied in an RGBA image and reduced to
+// what the channel format can represent whenever they are written, so
+// that drawing gives the same results as on an image of that format.
+type memimage struct {
+	pix   Pix
+	r     Rectangle
+	clipr Rectangle
+	repl  bool
+	m     *image.RGBA // pixel storage, shared by a screen's windows
+	delta Point       // storage point minus logical point
+	scr   *memscreen  // screen of a window, else nil
+
+	alpha bool // pix has an alpha channel
+	rgb8  bool // pix holds 8-bit colors exactly
+
+	// Font cache, set up by the 'i' and 'l' messages
+	fchar  []memfchar
+	ascent int
+}
+
+// memfchar describes a glyph loaded into a font cache image.
+type memfchar struct {
+	r     Rectangle // glyph in the cache image
+	left  int
+	width int
+}
+
+// memscreen is a screen of a memory display.
+type memscreen struct {
+	image  *memimage
+	fill   *memimage
+	public bool
+}
+
+// newmemimage returns an image with its own storage, filled with val.
+func newmemimage(pix Pix, r Rectangle, clipr Rectangle, repl bool, val uint32) *memimage {
+	i := &memimage{
+		pix:   pix,
+		r:     r,
+		clipr: clipr,
+		repl:  repl,
+		m:     image.NewRGBA(image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)),
+	}
+	i.setpix()
+	i.fill(r, val)
+	return i
+}
+
+// setpix records the properties of i's channel format.
+func (i *memimage) setpix() {
+	i.alpha = false
+	i.rgb8 = true
+	for c := i.pix; c != 0; c >>= 8 {
+		switch int(c>>4) & 0xF {
+		case CAlpha:
+			i.alpha = true
+		case CRed, CGreen, CBlue, CIgnore:
+		default:
+			i.rgb8 = false
+		}
+		if c&0xF != 8 {
+			i.rgb8 = false
+		}
+	}
+}
+
+// bounds returns the part of i.r that has storage. For a window it
+// may be less than i.r, where the window extends beyond its screen.
+func (i *memimage) bounds() Rectangle {
+	b := i.m.Rect
+	r, _ := i.r.Clip(Rect(b.Min.X, b.Min.Y, b.Max.X, b.Max.Y).Sub(i.delta))
+	return r
+}
+
+// get returns the pixel at logical point p, which must be in i.bounds().
+func (i *memimage) get(p Point) [4]uint8 {
+	o := i.m.PixOffset(p.X+i.delta.X, p.Y+i.delta.Y)
+	return [4]uint8{i.m.Pix[o], i.m.Pix[o+1], i.m.Pix[o+2], i.m.Pix[o+3]}
+}
+
+// set stores c at logical point p, which must be in i.bounds().
+func (i *memimage) set(p Point, c [4]uint8) {
+	c = i.quantize(c)
+	o := i.m.PixOffset(p.X+i.delta.X, p.Y+i.delta.Y)
+	copy(i.m.Pix[o:o+4], c[:])
+}
+
+// quantize reduces c to what i's channel format holds.
+func (i *memimage) quantize(c [4]uint8) [4]uint8 {
+	if i.rgb8 {
+		if !i.alpha {
+			c[3] = 0xFF
+		}
+		return c
+	}
+	r, g, b, a := pixToRGBA(i.pix, rgbaToPix(i.pix, c[0], c[1], c[2], c[3]))
+	return [4]uint8{r, g, b, a}
+}
+
+// sample returns the pixel of i at p, replicating if i does.
+func (i *memimage) sample(p Point) [4]uint8 {
+	if i.repl {
+		p = Drawrepl(i.r, p)
+		if !p.In(i.bounds()) {
+			return [4]uint8{}
+		}
+	}
+	return i.get(p)
+}
+
+// maskval returns the coverage of i at p used as a mask: its alpha
+// if it has an alpha channel, otherwise its grey level.
+func (i *memimage) maskval(p Point) uint8 {
+	c := i.sample(p)
+	if i.alpha {
+		return c[3]
+	}
+	return rgb2k(c[0], c[1], c[2])
+}
+
+// fill sets the pixels of i in r to the RGBA color val, without
+// compositing.
+func (i *memimage) fill(r Rectangle, val uint32) {
+	r, ok := r.Clip(i.bounds())
+	if !ok {
+		return
+	}
+	c := i.quantize([4]uint8{uint8(val >> 24), uint8(val >> 16), uint8(val >> 8), uint8(val)})
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		o := i.m.PixOffset(r.Min.X+i.delta.X, y+i.delta.Y)
+		for x := r.Min.X; x < r.Max.X; x++ {
+			copy(i.m.Pix[o:o+4], c[:])
+			o += 4
+		}
+	}
+}
+
+// rgb2k converts a color to grey, as the RGB2K macro in memdraw.
+func rgb2k(r, g, b uint8) uint8 {
+	return uint8((156763*uint32(r) + 307758*uint32(g) + 59769*uint32(b)) >> 19)
+}
+
+// rgbaToPix packs 8-bit red, green, blue and alpha into a raw pixel
+// value of the channel format. It is the inverse of pixToRGBA.
+func rgbaToPix(pix Pix, r, g, b, a uint8) uint32 {
+	var v uint32
+	shift := uint(0)
+	for c := pix; c != 0; c >>= 8 {
+		n := uint(c & 0xF)
+		var cv uint32
+		switch int(c>>4) & 0xF {
+		case CRed:
+			cv = uint32(r)
+		case CGreen:
+			cv = uint32(g)
+		case CBlue:
+			cv = uint32(b)
+		case CGrey:
+			cv = uint32(rgb2k(r, g, b))
+		case CAlpha:
+			cv = uint32(a)
+		case CMap:
+			v |= uint32(Rgb2cmap(int(r), int(g), int(b))) << shift
+			shift += n
+			continue
+		}
+		if n <= 8 {
+			cv >>= 8 - n
+		} else {
+			cv <<= n - 8
+		}
+		v |= cv << shift
+		shift += n
+	}
+	return v
+}
+
+// setPixelValue stores the raw value of the pixel at column x in a
+// scan line laid out as for pixelValue.
+func setPixelValue(row []byte, minx, x, depth int, v uint32) {
+	if depth < 8 {
+		bit := x*depth - (minx*depth)&^7
+		shift := uint(8 - depth - bit&7)
+		m := byte(1<<uint(depth)-1) << shift
+		row[bit>>3] = row[bit>>3]&^m | byte(v)<<shift&m
+		return
+	}
+	nb := depth / 8
+	off := (x - minx) * nb
+	for k := 0; k < nb; k++ {
+		row[off+k] = byte(v >> uint(8*k))
+	}
+}
+
+// load stores pixel data laid out as for Load into r of i.
+func (i *memimage) load(r Rectangle, data []byte) {
+	depth := chantodepth(i.pix)
+	bpl := bytesPerLine(r, depth)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := data[(y-r.Min.Y)*bpl:]
+		for x := r.Min.X; x < r.Max.X; x++ {
+			cr, cg, cb, ca := pixToRGBA(i.pix, pixelValue(row, r.Min.X, x, depth))
+			o := i.m.PixOffset(x+i.delta.X, y+i.delta.Y)
+			i.m.Pix[o], i.m.Pix[o+1], i.m.Pix[o+2], i.m.Pix[o+3] = cr, cg, cb, ca
+		}
+	}
+}
+
+// cload decompresses data, a block of compressed lines as sent in a
+// 'Y' message, into r of i, and returns the number of bytes of data
+// used. Port of 9front cloadmemimage().
+func (i *memimage) cload(r Rectangle, data []byte) (int, error) {
+	buf, u, err := uncompress(data, bytesPerLine(r, chantodepth(i.pix)), r.Dy())
+	if err != nil {
+		return 0, err
+	}
+	i.load(r, buf)
+	return u, nil
+}
+
+// uncompress expands nline lines of bpl bytes from the compressed
+// data, returning them and the number of bytes of data used.
+func uncompress(data []byte, bpl, nline int) ([]byte, int, error) {
+	buf := make([]byte, bpl*nline)
+	var mem [NMEM]byte
+	memp := 0
+	u := 0
+	short := errors.New("memdraw: short compressed data")
+	phase := errors.New("memdraw: compressed data crosses a line")
+	for k := 0; k < len(buf); {
+		eline := (k/bpl + 1) * bpl
+		if u == len(data) {
+			return nil, 0, short
+		}
+		c := data[u]
+		u++
+		if c >= 128 {
+			for cnt := int(c) - 128 + 1; cnt != 0; cnt-- {
+				if u == len(data) {
+					return nil, 0, short
+				}
+				if k == eline {
+					return nil, 0, phase
+				}
+				buf[k] = data[u]
+				mem[memp] = data[u]
+				k, u, memp = k+1, u+1, (memp+1)%NMEM
+			}
+		} else {
+			if u == len(data) {
+				return nil, 0, short
+			}
+			offs := int(data[u]) + int(c&3)<<8 + 1
+			u++
+			omemp := (memp - offs + NMEM) % NMEM
+			for cnt := int(c>>2) + NMATCH; cnt != 0; cnt-- {
+				if k == eline {
+					return nil, 0, phase
+				}
+				buf[k] = mem[omemp]
+				mem[memp] = mem[omemp]
+				k, memp, omemp = k+1, (memp+1)%NMEM, (omemp+1)%NMEM
+			}
+		}
+	}
+	return buf, u, nil
+}
+
+// unload returns the pixels of i in r laid out as Unload reads them.
+func (i *memimage) unload(r Rectangle) []byte {
+	depth := chantodepth(i.pix)
+	bpl := bytesPerLine(r, depth)
+	data := make([]byte, bpl*r.Dy())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := data[(y-r.Min.Y)*bpl:]
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := i.get(Pt(x, y))
+			setPixelValue(row, r.Min.X, x, depth, rgbaToPix(i.pix, c[0], c[1], c[2], c[3]))
+		}
+	}
+	return data
+}
+
+// Porter-Duff terms of the compositing operators, as in 9front's
+// draw.h: whether the source is kept where the destination is opaque
+// (SinD) and where it is clear (SoutD), and likewise the destination.
+const (
+	opSinD  = 8
+	opDinS  = 4
+	opSoutD = 2
+	opDoutS = 1
+)
+
+var opbits = [Ncomp]uint8{
+	SoverD: opSinD | opSoutD | opDoutS,
+	DoverS: opDinS | opDoutS | opSoutD,
+	SatopD: opSinD | opDoutS,
+	DatopS: opDinS | opSoutD,
+	SxorD:  opSoutD | opDoutS,
+	DxorS:  opSoutD | opDoutS,
+	Clear:  0,
+	S:      opSinD | opSoutD,
+	D:      opDinS | opDoutS,
+	SoutD:  opSoutD,
+	DoutS:  opDoutS,
+	SinD:   opSinD,
+	DinS:   opDinS,
+}
+
+// mul8 multiplies two 8-bit fractions.
+func mul8(a, b uint8) uint8 {
+	return uint8((uint32(a)*uint32(b) + 127) / 255)
+}
+
+// composite returns the result of (s in ma) op d for premultiplied
+// colors, where bits are the operator's Porter-Duff terms.
+func composite(s [4]uint8, ma uint8, d [4]uint8, bits uint8) [4]uint8 {
+	if ma != 0xFF {
+		for k := range s {
+			s[k] = mul8(s[k], ma)
+		}
+	}
+	sa, da := uint32(s[3]), uint32(d[3])
+	var fs, fd uint32
+	if bits&opSinD != 0 {
+		fs += da
+	}
+	if bits&opSoutD != 0 {
+		fs += 255 - da
+	}
+	if bits&opDinS != 0 {
+		fd += sa
+	}
+	if bits&opDoutS != 0 {
+		fd += 255 - sa
+	}
+	var out [4]uint8
+	for k := range out {
+		v := (uint32(s[k])*fs + uint32(d[k])*fd + 127) / 255
+		if v > 255 {
+			v = 255
+		}
+		out[k] = uint8(v)
+	}
+	return out
+}
+
+// memdraw composites src through mask onto r of dst, where sp and mp
+// are the points of src and mask that correspond to r.Min. A nil mask
+// is opaque. Source and mask are clipped as in 9front's drawclip():
+// a replicated image by its clipping rectangle alone, any other by its
+// rectangle as well.
+// Port of 9front memdraw(), without its special cases.
+func memdraw(dst *memimage, r Rectangle, src *memimage, sp Point, mask *memimage, mp Point, op Op) {
+	if op < 0 || op >= Ncomp {
+		return
+	}
+	sd := sp.Sub(r.Min)
+	md := mp.Sub(r.Min)
+	var ok bool
+	if r, ok = r.Clip(dst.bounds()); !ok {
+		return
+	}
+	if r, ok = r.Clip(dst.clipr); !ok {
+		return
+	}
+	if r, ok = clipsrc(r, src, sd); !ok {
+		return
+	}
+	if mask != nil {
+		if r, ok = clipsrc(r, mask, md); !ok {
+			return
+		}
+	}
+
+	// Copy in an order that reads overlapping pixels before they are
+	// overwritten, as when scrolling an image within itself.
+	x0, x1, dx := r.Min.X, r.Max.X, 1
+	y0, y1, dy := r.Min.Y, r.Max.Y, 1
+	if src.m == dst.m {
+		v := sd.Add(src.delta).Sub(dst.delta)
+		if v.Y < 0 || v.Y == 0 && v.X < 0 {
+			x0, x1, dx = r.Max.X-1, r.Min.X-1, -1
+			y0, y1, dy = r.Max.Y-1, r.Min.Y-1, -1
+		}
+	}
+	bits := opbits[op]
+	for y := y0; y != y1; y += dy {
+		for x := x0; x != x1; x += dx {
+			p := Pt(x, y)
+			ma := uint8(0xFF)
+			if mask != nil {
+				ma = mask.maskval(p.Add(md))
+			}
+			dst.set(p, composite(src.sample(p.Add(sd)), ma, dst.get(p), bits))
+		}
+	}
+}
+
+// clipsrc clips r, in destination coordinates, to the part for which
+// source image i, offset by d from the destination, has pixels.
+func clipsrc(r Rectangle, i *memimage, d Point) (Rectangle, bool) {
+	var ok bool
+	if !i.repl {
+		if r, ok = r.Clip(i.bounds().Sub(d)); !ok {
+			return r, false
+		}
+	}
+	return r.Clip(i.clipr.Sub(d))
+}
+
+// covmask marks the pixels covered by a shape, within a rectangle.
+type covmask struct {
+	r Rectangle
+	b []bool
+}
+
+// newcovmask returns an empty mask for the part of dst that can be
+// drawn on within bounds, or nil if there is none.
+func newcovmask(dst *memimage, bounds Rectangle) *covmask {
+	r, ok := bounds.Clip(dst.bounds())
+	if !ok {
+		return nil
+	}
+	if r, ok = r.Clip(dst.clipr); !ok {
+		return nil
+	}
+	return &covmask{r: r, b: make([]bool, r.Dx()*r.Dy())}
+}
+
+// fillspan marks pixels x0 through x1-1 of row y.
+func (c *covmask) fillspan(y, x0, x1 int) {
+	if y < c.r.Min.Y || y >= c.r.Max.Y {
+		return
+	}
+	x0 = max(x0, c.r.Min.X)
+	x1 = min(x1, c.r.Max.X)
+	row := (y - c.r.Min.Y) * c.r.Dx()
+	for x := x0; x < x1; x++ {
+		c.b[row+x-c.r.Min.X] = true
+	}
+}
+
+// fpoint is a point with fractional coordinates; pixel x covers
+// [x, x+1) and is sampled at its center.
+type fpoint struct {
+	x, y float64
+}
+
+// center returns the center of the pixel at p.
+func center(p Point) fpoint {
+	return fpoint{float64(p.X) + 0.5, float64(p.Y) + 0.5}
+}
+
+// fillpoly marks the pixels whose centers lie inside the polygon.
+// A pixel is inside if the winding number around it, masked by wind,
+// is not zero: wind of ^0 gives the non-zero rule and 1 the even-odd
+// rule, as in 9front's fillpoly().
+func (c *covmask) fillpoly(pts []fpoint, wind int) {
+	type cross struct {
+		x float64
+		d int
+	}
+	var xs []cross
+	for y := c.r.Min.Y; y < c.r.Max.Y; y++ {
+		yc := float64(y) + 0.5
+		xs = xs[:0]
+		for i := range pts {
+			a, b := pts[i], pts[(i+1)%len(pts)]
+			d := 1
+			if a.y > b.y {
+				a, b = b, a
+				d = -1
+			}
+			if yc < a.y || yc >= b.y {
+				continue
+			}
+			xs = append(xs, cross{a.x + (yc-a.y)*(b.x-a.x)/(b.y-a.y), d})
+		}
+		sort.Slice(xs, func(i, j int) bool { return xs[i].x < xs[j].x })
+		cnt := 0
+		for i := 0; i+1 < len(xs); i++ {
+			cnt += xs[i].d
+			if cnt&wind != 0 {
+				c.fillspan(y, int(math.Ceil(xs[i].x-0.5)), int(math.Ceil(xs[i+1].x-0.5)))
+			}
+		}
+	}
+}
+
+// disc marks the pixels whose centers lie within rad of p.
+func (c *covmask) disc(p fpoint, rad float64) {
+	for y := int(math.Floor(p.y - rad)); y <= int(math.Ceil(p.y+rad)); y++ {
+		dy := float64(y) + 0.5 - p.y
+		if dy*dy > rad*rad {
+			continue
+		}
+		dx := math.Sqrt(rad*rad - dy*dy)
+		c.fillspan(y, int(math.Ceil(p.x-dx-0.5)), int(math.Floor(p.x+dx-0.5))+1)
+	}
+}
+
+// line marks the pixels of a line from p0 to p1 of thickness
+// 1+2*radius with the given end styles.
+func (c *covmask) line(p0, p1 Point, end0, end1, radius int) {
+	a, b := center(p0), center(p1)
+	ux, uy := b.x-a.x, b.y-a.y
+	if l := math.Hypot(ux, uy); l > 0 {
+		ux, uy = ux/l, uy/l
+	} else {
+		ux, uy = 1, 0
+	}
+	w := float64(2*radius+1) / 2
+	// Each end moves the end of the shaft: a square end reaches half a
+	// pixel beyond its point so that the point itself is drawn, and an
+	// arrow's shaft stops at the back of its head.
+	a = c.lineend(a, -ux, -uy, w, end0, radius)
+	b = c.lineend(b, ux, uy, w, end1, radius)
+	nx, ny := -uy*w, ux*w
+	c.fillpoly([]fpoint{
+		{a.x + nx, a.y + ny},
+		{b.x + nx, b.y + ny},
+		{b.x - nx, b.y - ny},
+		{a.x - nx, a.y - ny},
+	}, ^0)
+}
+
+// lineend marks the cap of a line end at p facing direction (ux, uy)
+// and returns where the shaft should end.
+// Port of 9front arrowend() for the arrow head.
+func (c *covmask) lineend(p fpoint, ux, uy, w float64, end, radius int) fpoint {
+	switch end & Endmask {
+	case Enddisc:
+		c.disc(p, float64(radius)+0.5)
+		return p
+	case Endarrow:
+		// The tip is at the outer edge of the end pixel.
+		p = fpoint{p.x + ux*0.5, p.y + uy*0.5}
+		x1, x2, x3 := arrowsize(end)
+		nx, ny := -uy, ux
+		base := fpoint{p.x - ux*float64(x1), p.y - uy*float64(x1)}
+		barb := fpoint{p.x - ux*float64(x2), p.y - uy*float64(x2)}
+		bw := w + float64(x3)
+		c.fillpoly([]fpoint{
+			{base.x + nx*w, base.y + ny*w},
+			{barb.x + nx*bw, barb.y + ny*bw},
+			p,
+			{barb.x - nx*bw, barb.y - ny*bw},
+			{base.x - nx*w, base.y - ny*w},
+		}, ^0)
+		return base
+	}
+	return fpoint{p.x + ux*0.5, p.y + uy*0.5}
+}
+
+// ellipse marks the pixels of an ellipse centered at p with semi-axes
+// a and b: a ring of thickness 1+2*thick, or the whole ellipse if thick
+// is negative. If arc is set only the part from angle alpha sweeping
+// phi degrees counterclockwise is marked, as a pie slice when filled.
+func (c *covmask) ellipse(p Point, a, b, thick int, arc bool, alpha, phi int) {
+	a, b = abs(a), abs(b)
+	ring := thick >= 0
+	if !ring {
+		thick = 0
+	}
+	oa, ob := float64(a+thick)+0.5, float64(b+thick)+0.5
+	ia, ib := float64(a-thick)-0.5, float64(b-thick)-0.5
+	for y := c.r.Min.Y; y < c.r.Max.Y; y++ {
+		dy := float64(y - p.Y)
+		for x := c.r.Min.X; x < c.r.Max.X; x++ {
+			dx := float64(x - p.X)
+			if dx*dx/(oa*oa)+dy*dy/(ob*ob) > 1 {
+				continue
+			}
+			if ring && ia > 0 && ib > 0 && dx*dx/(ia*ia)+dy*dy/(ib*ib) < 1 {
+				continue
+			}
+			if arc && (dx != 0 || dy != 0) {
+				deg := math.Atan2(-dy, dx) * 180 / math.Pi
+				if math.Mod(deg-float64(alpha)+720, 360) > float64(phi) {
+					continue
+				}
+			}
+			c.b[(y-c.r.Min.Y)*c.r.Dx()+x-c.r.Min.X] = true
+		}
+	}
+}
+
+// memfill composites src onto the pixels of dst marked in c, with sd
+// the offset from a point of dst to the corresponding point of src.
+func memfill(dst *memimage, c *covmask, src *memimage, sd Point, op Op) {
+	if c == nil || op < 0 || op >= Ncomp {
+		return
+	}
+	r, ok := clipsrc(c.r, src, sd)
+	if !ok {
+		return
+	}
+	bits := opbits[op]
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := (y - c.r.Min.Y) * c.r.Dx()
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if !c.b[row+x-c.r.Min.X] {
+				continue
+			}
+			p := Pt(x, y)
+			dst.set(p, composite(src.sample(p.Add(sd)), 0xFF, dst.get(p), bits))
+		}
+	}
+}
+
+// memline draws a line on dst with src aligned so that sp is at p0.
+// It plays the role of 9front memline(), but coverage comes from
+// covmask rather than from a port of libmemdraw's line algorithm.
+func memline(dst *memimage, p0, p1 Point, end0, end1, radius int, src *memimage, sp Point, op Op) {
+	m := max(endmargin(end0, radius), endmargin(end1, radius))
+	bounds := Rect(min(p0.X, p1.X), min(p0.Y, p1.Y), max(p0.X, p1.X)+1, max(p0.Y, p1.Y)+1)
+	c := newcovmask(dst, bounds.Inset(-m))
+	if c == nil {
+		return
+	}
+	c.line(p0, p1, end0, end1, radius)
+	memfill(dst, c, src, sp.Sub(p0), op)
+}
+
+// mempoly draws the segments of a polyline, each as a separate line
+// joined to the next by discs, with src aligned so that sp is at p[0].
+// In the role of 9front mempoly(), built on memline above.
+func mempoly(dst *memimage, p []Point, end0, end1, radius int, src *memimage, sp Point, op Op) {
+	for i := 1; i < len(p); i++ {
+		e0, e1 := Enddisc, Enddisc
+		if i == 1 {
+			e0 = end0
+		}
+		if i == len(p)-1 {
+			e1 = end1
+		}
+		memline(dst, p[i-1], p[i], e0, e1, radius, src, sp.Add(p[i-1].Sub(p[0])), op)
+	}
+}
+
+// memfillpoly fills a polygon whose vertices lie on pixel corners,
+// with src aligned so that sp is at p[0].
+// In the role of 9front memfillpoly(); the polygon is scan converted
+// by covmask, not by libmemdraw's fillpoly code.
+func memfillpoly(dst *memimage, p []Point, wind int, src *memimage, sp Point, op Op) {
+	if len(p) < 3 {
+		return
+	}
+	bounds := Rpt(p[0], p[0])
+	pts := make([]fpoint, len(p))
+	for i, q := range p {
+		bounds = bounds.Combine(Rpt(q, q.Add(Pt(1, 1))))
+		pts[i] = fpoint{float64(q.X), float64(q.Y)}
+	}
+	c := newcovmask(dst, bounds)
+	if c == nil {
+		return
+	}
+	c.fillpoly(pts, wind)
+	memfill(dst, c, src, sp.Sub(p[0]), op)
+}
+
+// memellipse draws an ellipse or arc with src aligned so that sp is
+// at the center; thick is negative for a filled one.
+// In the role of 9front memellipse() and memarc(), with the shape
+// rasterized by covmask.
+func memellipse(dst *memimage, p Point, a, b, thick int, src *memimage, sp Point, arc bool, alpha, phi int, op Op) {
+	m := max(thick, 0) + 1
+	c := newcovmask(dst, Rect(p.X-abs(a)-m, p.Y-abs(b)-m, p.X+abs(a)+m+1, p.Y+abs(b)+m+1))
+	if c == nil {
+		return
+	}
+	c.ellipse(p, a, b, thick, arc, alpha, phi)
+	memfill(dst, c, src, sp.Sub(p), op)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -59,9 +59,6 @@ func (mc *Mousectl) Menuhit(but int, scr *Image, menu *Menu) int {
 	}
 
 	f := d.DefaultFont
-	if f == nil {
-		return -1
-	}
 
 	// Count items and find max width
 	var items []string
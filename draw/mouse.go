@@ -1,6 +1,7 @@
 package draw
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"os"
@@ -9,6 +10,25 @@ import (
 	"strings"
 )
 
+// Mouse buttons 4 and 5 are the scroll wheel. Each notch of the wheel
+// gives a message with one of them held and then one with it released.
+const (
+	ScrollUp   = 8  // button 4: the wheel turned away from the user
+	ScrollDown = 16 // button 5: the wheel turned towards the user
+)
+
+// Scroll returns -1 if m has the wheel turned up, 1 if turned down,
+// and 0 otherwise.
+func (m Mouse) Scroll() int {
+	switch {
+	case m.Buttons&ScrollUp != 0:
+		return -1
+	case m.Buttons&ScrollDown != 0:
+		return 1
+	}
+	return 0
+}
+
 // InitMouse opens the mouse device and returns a Mousectl.
 // If file is empty, it defaults to /dev/mouse.
 // The image i is the associated display image (used for flushing).
@@ -30,6 +50,28 @@ func InitMouse(file string, i *Image) (*Mousectl, error) {
 		cfd = nil
 	}
 
+	return newMousectl(mfd, cfd, i), nil
+}
+
+// InitMouseContext is like InitMouse, but the returned Mousectl is
+// closed when ctx is done.
+func InitMouseContext(ctx context.Context, file string, i *Image) (*Mousectl, error) {
+	mc, err := InitMouse(file, i)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			mc.Close()
+		case <-mc.done:
+		}
+	}()
+	return mc, nil
+}
+
+// newMousectl wraps an open mouse file and starts its reader.
+func newMousectl(mfd, cfd *os.File, i *Image) *Mousectl {
 	var d *Display
 	if i != nil {
 		d = i.Display
@@ -42,22 +84,42 @@ func InitMouse(file string, i *Image) (*Mousectl, error) {
 		file:    mfd,
 		cfd:     cfd,
 		image:   i,
+		done:    make(chan struct{}),
+		stop:    make(chan struct{}),
 	}
 
-	go mc.readproc()
-	return mc, nil
+	go mc.readproc(mfd)
+	return mc
 }
 
 // readproc reads mouse events in a goroutine.
 // The mouse message format is: type[1] x[12] y[12] buttons[12] msec[12]
 // where type is 'm' for mouse or 'r' for resize.
-func (mc *Mousectl) readproc() {
+// When the mouse file fails or is closed, C and Resize are closed
+// and the done channel is signalled.
+//
+// Messages are dropped if nobody is reading C, except once a
+// MouseReader has taken the feed: it must see every message to keep
+// track of the buttons, so they are sent to it without dropping.
+func (mc *Mousectl) readproc(file *os.File) {
+	defer func() {
+		mc.mu.Lock()
+		mc.exited = true
+		if mc.feed != nil {
+			close(mc.feed)
+		}
+		mc.mu.Unlock()
+		close(mc.C)
+		close(mc.Resize)
+		close(mc.done)
+	}()
+
 	buf := make([]byte, 1+5*12)
 	nerr := 0
 	for {
-		n, err := mc.file.Read(buf)
+		n, err := file.Read(buf)
 		if n != 1+4*12 {
-			if err != nil || mc.file == nil {
+			if err != nil {
 				break
 			}
 			nerr++
@@ -82,9 +144,19 @@ func (mc *Mousectl) readproc() {
 			m.Y = atoiField(buf[1+12 : 1+2*12])
 			m.Buttons = atoiField(buf[1+2*12 : 1+3*12])
 			m.Msec = uint32(atoiField(buf[1+3*12 : 1+4*12]))
-			select {
-			case mc.C <- m:
-			default:
+			mc.mu.Lock()
+			feed := mc.feed
+			mc.mu.Unlock()
+			if feed != nil {
+				select {
+				case feed <- m:
+				case <-mc.stop:
+				}
+			} else {
+				select {
+				case mc.C <- m:
+				default:
+				}
 			}
 			// Update after send so readmouse() gets the right value
 			mc.Mouse = m
@@ -139,16 +211,39 @@ func (mc *Mousectl) SetCursor(c *Cursor) {
 	mc.cfd.Write(buf[:])
 }
 
-// Close closes the mouse connection.
+// Close closes the mouse connection. Closing the mouse file unblocks
+// the reader, which then closes C and Resize; use Done to wait for it.
+// Close may be called more than once.
 func (mc *Mousectl) Close() {
-	if mc.cfd != nil {
-		mc.cfd.Close()
-		mc.cfd = nil
+	mc.once.Do(func() {
+		close(mc.stop)
+		if mc.cfd != nil {
+			mc.cfd.Close()
+		}
+		if mc.file != nil {
+			mc.file.Close()
+		}
+	})
+}
+
+// takefeed diverts mouse messages from C to a channel that receives
+// every one, returning nil if the reader has already exited.
+func (mc *Mousectl) takefeed() chan Mouse {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.exited {
+		return nil
 	}
-	if mc.file != nil {
-		mc.file.Close()
-		mc.file = nil
+	if mc.feed == nil {
+		mc.feed = make(chan Mouse, 32)
 	}
+	return mc.feed
+}
+
+// Done returns a channel that is closed once the reader has exited
+// and C and Resize have been closed.
+func (mc *Mousectl) Done() <-chan struct{} {
+	return mc.done
 }
 
 // atoiField parses a whitespace-padded decimal field from a Plan 9 mouse message.
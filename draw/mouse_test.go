@@ -2,7 +2,9 @@ package draw
 
 import (
 	"fmt"
+	"os"
 	"testing"
+	"time"
 )
 
 // TestAtoiField tests the Plan 9 mouse message field parser.
@@ -109,3 +111,91 @@ func TestMouseButtonBits(t *testing.T) {
 		t.Errorf("all buttons = %d, want 7", all)
 	}
 }
+
+// TestMousectlClose verifies that Close unblocks the reader and that
+// C, Resize, and Done are all closed afterwards.
+func TestMousectlClose(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	mc := newMousectl(r, nil, nil)
+
+	got := make(chan Mouse, 1)
+	go func() {
+		m, ok := <-mc.C
+		if ok {
+			got <- m
+		}
+	}()
+	msg := fmt.Sprintf("m%12d%12d%12d%12d", 10, 20, 1, 500)
+	deadline := time.After(2 * time.Second)
+Send:
+	for {
+		if _, err := w.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case m := <-got:
+			if m.X != 10 || m.Y != 20 || m.Buttons != 1 {
+				t.Errorf("got %+v, want (10,20) buttons 1", m)
+			}
+			break Send
+		case <-deadline:
+			t.Fatal("timed out waiting for mouse event")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	mc.Close()
+	mc.Close() // must be safe to call twice
+	select {
+	case <-mc.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done not signalled after Close")
+	}
+	if _, ok := <-mc.C; ok {
+		t.Error("C still open after Close")
+	}
+	if _, ok := <-mc.Resize; ok {
+		t.Error("Resize still open after Close")
+	}
+}
+
+// TestMousectlWriterClosed verifies that the reader exits when the
+// mouse file reaches EOF.
+func TestMousectlWriterClosed(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := newMousectl(r, nil, nil)
+	defer mc.Close()
+	w.Close()
+	select {
+	case <-mc.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done not signalled after EOF")
+	}
+}
+
+// TestMouseScroll verifies wheel detection from buttons 4 and 5.
+func TestMouseScroll(t *testing.T) {
+	tests := []struct {
+		buttons int
+		want    int
+	}{
+		{0, 0},
+		{1, 0},
+		{ScrollUp, -1},
+		{ScrollDown, 1},
+		{4 | ScrollDown, 1},
+	}
+	for _, tt := range tests {
+		if got := (Mouse{Buttons: tt.buttons}).Scroll(); got != tt.want {
+			t.Errorf("Scroll() with buttons %d = %d, want %d", tt.buttons, got, tt.want)
+		}
+	}
+}
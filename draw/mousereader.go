@@ -0,0 +1,161 @@
+package draw
+
+// MouseEventKind says what happened in a MouseEvent.
+type MouseEventKind int
+
+const (
+	MMove    MouseEventKind = iota // moved with no buttons held
+	MPress                         // a button went down with none held before
+	MRelease                       // one or more buttons went up
+	MDrag                          // moved with buttons held
+	MChord                         // a button went down while others were held
+	MScroll                        // the wheel turned
+)
+
+var mouseEventKindNames = [...]string{
+	MMove:    "move",
+	MPress:   "press",
+	MRelease: "release",
+	MDrag:    "drag",
+	MChord:   "chord",
+	MScroll:  "scroll",
+}
+
+func (k MouseEventKind) String() string {
+	if k >= 0 && int(k) < len(mouseEventKindNames) {
+		return mouseEventKindNames[k]
+	}
+	return "MouseEventKind(?)"
+}
+
+// Presses of the same button closer than ClickMsec milliseconds apart
+// and within ClickSlop pixels of each other make a multiple click.
+const (
+	ClickMsec = 500
+	ClickSlop = 3
+)
+
+// A MouseEvent is a mouse message interpreted against the one before.
+// Mouse is the new state. Prev holds the buttons before the message
+// and Button the buttons that changed: those pressed for MPress and
+// MChord, those released for MRelease. For example, acme's cut chord
+// is an MChord with Prev 1 and Button 2.
+//
+// The wheel buttons, ScrollUp and ScrollDown, are not reported as
+// buttons. Instead each notch of the wheel gives an MScroll event with
+// Scroll -1 for up and 1 for down.
+//
+// For MPress, Clicks counts the presses in a series: 1 for a single
+// click, 2 for a double click, 3 for a triple click, and so on.
+type MouseEvent struct {
+	Kind MouseEventKind
+	Mouse
+	Prev   int
+	Button int
+	Scroll int
+	Clicks int
+}
+
+// A MouseReader turns the mouse messages of a Mousectl into
+// MouseEvents, sent on C. C is closed when the Mousectl's C is.
+// A MouseReader takes the Mousectl's messages for itself, so nothing
+// more is sent on the Mousectl's C. Unlike C, no message is dropped
+// when the program is slow to read: the Mousectl waits instead.
+type MouseReader struct {
+	C    chan MouseEvent
+	mc   *Mousectl
+	feed chan Mouse
+	st   mousestate
+}
+
+// NewMouseReader starts a MouseReader on mc.
+func NewMouseReader(mc *Mousectl) *MouseReader {
+	mr := &MouseReader{
+		C:    make(chan MouseEvent, 8),
+		mc:   mc,
+		feed: mc.takefeed(),
+	}
+	go mr.readproc()
+	return mr
+}
+
+// Read returns the next event, blocking until one is available.
+func (mr *MouseReader) Read() MouseEvent {
+	return <-mr.C
+}
+
+// readproc interprets mouse messages in a goroutine.
+func (mr *MouseReader) readproc() {
+	defer close(mr.C)
+	if mr.feed == nil {
+		return
+	}
+	for m := range mr.feed {
+		for _, e := range mr.st.events(m) {
+			mr.C <- e
+		}
+	}
+}
+
+// mousestate is what a MouseReader remembers between messages.
+type mousestate struct {
+	last   Mouse // without the wheel buttons
+	wheel  int   // wheel buttons held
+	press  Mouse // the last press, with Buttons the button pressed
+	clicks int   // presses in the current series
+}
+
+// click counts a press of button b, returning its place in a series
+// of multiple clicks.
+func (st *mousestate) click(m Mouse, b int) int {
+	d := m.Point.Sub(st.press.Point)
+	if st.clicks > 0 && b == st.press.Buttons && m.Msec-st.press.Msec < ClickMsec &&
+		abs(d.X) <= ClickSlop && abs(d.Y) <= ClickSlop {
+		st.clicks++
+	} else {
+		st.clicks = 1
+	}
+	st.press = m
+	st.press.Buttons = b
+	return st.clicks
+}
+
+// events interprets m. A message that releases some buttons and
+// presses others gives a release followed by a press or chord; one
+// that changes nothing gives no events.
+func (st *mousestate) events(m Mouse) []MouseEvent {
+	const wheel = ScrollUp | ScrollDown
+	turned := m.Buttons &^ st.wheel & wheel
+	st.wheel = m.Buttons & wheel
+	m.Buttons &^= wheel
+
+	prev := st.last
+	st.last = m
+	down := m.Buttons &^ prev.Buttons
+	up := prev.Buttons &^ m.Buttons
+	var ev []MouseEvent
+	if up != 0 {
+		ev = append(ev, MouseEvent{Kind: MRelease, Mouse: m, Prev: prev.Buttons, Button: up})
+	}
+	if down != 0 {
+		e := MouseEvent{Kind: MPress, Mouse: m, Prev: prev.Buttons &^ up, Button: down}
+		if prev.Buttons&m.Buttons != 0 {
+			e.Kind = MChord
+			st.clicks = 0
+		} else {
+			e.Clicks = st.click(m, down)
+		}
+		ev = append(ev, e)
+	}
+	if up == 0 && down == 0 && m.Point != prev.Point {
+		k := MMove
+		if m.Buttons != 0 {
+			k = MDrag
+		}
+		ev = append(ev, MouseEvent{Kind: k, Mouse: m, Prev: prev.Buttons})
+	}
+	if turned != 0 {
+		ev = append(ev, MouseEvent{Kind: MScroll, Mouse: m, Prev: m.Buttons, Scroll: Mouse{Buttons: turned}.Scroll()})
+	}
+	return ev
+}
@@ -0,0 +1,179 @@
+package draw
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMouseEvents(t *testing.T) {
+	var st mousestate
+	mouse := func(x, b int) Mouse { return Mouse{Point: Pt(x, 0), Buttons: b} }
+	ev := func(k MouseEventKind, m Mouse, prev, b int) MouseEvent {
+		e := MouseEvent{Kind: k, Mouse: m, Prev: prev, Button: b}
+		if k == MPress {
+			e.Clicks = 1
+		}
+		return e
+	}
+	scroll := func(m Mouse, n int) MouseEvent {
+		return MouseEvent{Kind: MScroll, Mouse: m, Prev: m.Buttons, Scroll: n}
+	}
+	tests := []struct {
+		m    Mouse
+		want []MouseEvent
+	}{
+		{mouse(1, 0), []MouseEvent{ev(MMove, mouse(1, 0), 0, 0)}},
+		{mouse(1, 0), nil},
+		{mouse(1, 1), []MouseEvent{ev(MPress, mouse(1, 1), 0, 1)}},
+		{mouse(2, 1), []MouseEvent{ev(MDrag, mouse(2, 1), 1, 0)}},
+		{mouse(2, 3), []MouseEvent{ev(MChord, mouse(2, 3), 1, 2)}},
+		{mouse(2, 1), []MouseEvent{ev(MRelease, mouse(2, 1), 3, 2)}},
+		{mouse(2, 5), []MouseEvent{ev(MChord, mouse(2, 5), 1, 4)}},
+		{mouse(3, 4), []MouseEvent{ev(MRelease, mouse(3, 4), 5, 1)}},
+		{mouse(3, 2), []MouseEvent{
+			ev(MRelease, mouse(3, 2), 4, 4),
+			ev(MPress, mouse(3, 2), 0, 2),
+		}},
+		{mouse(3, 0), []MouseEvent{ev(MRelease, mouse(3, 0), 2, 2)}},
+
+		// The wheel, alone and with a button held.
+		{mouse(3, ScrollUp), []MouseEvent{scroll(mouse(3, 0), -1)}},
+		{mouse(3, 0), nil},
+		{mouse(3, ScrollDown), []MouseEvent{scroll(mouse(3, 0), 1)}},
+		{mouse(3, 1|ScrollUp), []MouseEvent{
+			ev(MPress, mouse(3, 1), 0, 1),
+			scroll(mouse(3, 1), -1),
+		}},
+		{mouse(4, 1), []MouseEvent{ev(MDrag, mouse(4, 1), 1, 0)}},
+	}
+	for i, tt := range tests {
+		got := st.events(tt.m)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%d: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestMouseClicks(t *testing.T) {
+	var st mousestate
+	tests := []struct {
+		x, b int
+		msec uint32
+		want int // clicks reported for the press
+	}{
+		{10, 1, 1000, 1},
+		{11, 1, 1300, 2},
+		{10, 1, 1600, 3},
+		{10, 1, 1900, 4},
+		{10, 1, 2500, 1}, // too slow
+		{20, 1, 2600, 1}, // too far
+		{20, 2, 2700, 1}, // another button
+		{20, 1, 2800, 1},
+		{20, 3, 2850, 0}, // a chord ends the series
+		{20, 1, 2900, 1},
+	}
+	for i, tt := range tests {
+		if tt.b == 3 {
+			// Hold button 1 first, so that 2 makes a chord.
+			st.events(Mouse{Pt(tt.x, 0), 1, tt.msec - 10})
+		}
+		got := st.events(Mouse{Pt(tt.x, 0), tt.b, tt.msec})
+		if tt.b == 3 {
+			// Release just button 2 of the chord, leaving 1 held.
+			st.events(Mouse{Pt(tt.x, 0), 1, tt.msec + 10})
+		}
+		st.events(Mouse{Pt(tt.x, 0), 0, tt.msec + 20})
+		if n := len(got); n == 0 || got[n-1].Clicks != tt.want {
+			t.Errorf("%d: got %v, want %d clicks", i, got, tt.want)
+		}
+	}
+}
+
+func TestMouseReader(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := newMousectl(r, nil, nil)
+	defer mc.Close()
+	mr := NewMouseReader(mc)
+
+	for _, b := range []int{1, 3} {
+		fmt.Fprintf(w, "m%12d%12d%12d%12d", 10, 20, b, 500)
+		select {
+		case e := <-mr.C:
+			if e.Buttons != b {
+				t.Errorf("got %+v, want buttons %d", e, b)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for mouse event")
+		}
+	}
+	w.Close()
+	select {
+	case _, ok := <-mr.C:
+		if ok {
+			t.Error("C still open after EOF")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("C not closed after EOF")
+	}
+}
+
+func TestMouseReaderFlood(t *testing.T) {
+	// A pipe may run messages together; a packet socket, like the
+	// mouse file, gives one message per read.
+	ln, err := net.ListenUnix("unixpacket", &net.UnixAddr{Name: filepath.Join(t.TempDir(), "mouse"), Net: "unixpacket"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	w, err := net.DialUnix("unixpacket", nil, ln.Addr().(*net.UnixAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ln.AcceptUnix()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.File()
+	c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := newMousectl(r, nil, nil)
+	defer mc.Close()
+	mr := NewMouseReader(mc)
+
+	// Send the messages before reading any events, so a reader that
+	// dropped messages while busy would lose some.
+	const n = 500
+	go func() {
+		for i := 0; i < 2*n; i++ {
+			fmt.Fprintf(w, "m%12d%12d%12d%12d", 10, 20, 1-i%2, 1000*i)
+		}
+		w.Close()
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	var got []MouseEventKind
+	for e := range mr.C {
+		got = append(got, e.Kind)
+	}
+	if len(got) != 2*n {
+		t.Fatalf("got %d events, want %d", len(got), 2*n)
+	}
+	for i, k := range got {
+		want := MPress
+		if i%2 == 1 {
+			want = MRelease
+		}
+		if k != want {
+			t.Fatalf("event %d is %v, want %v", i, k, want)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package draw
 
 import (
-	"compress/zlib"
 	"fmt"
 	"io"
 	"os"
@@ -22,71 +21,182 @@ func (d *Display) ReadImageFile(name string) (*Image, error) {
 	return d.ReadImage(f)
 }
 
-// Creadimage reads a compressed image.
-func (d *Display) Creadimage(f io.Reader) (*Image, error) {
-	// Read header
-	header := make([]byte, 5*12)
-	n, err := io.ReadFull(f, header)
-	if err != nil {
-		return nil, err
+// ReadImageReader reads an image file in the format of image(6) from
+// r and loads it onto the display. Both uncompressed and compressed
+// files are accepted, as are old files whose header gives an ldepth
+// instead of a channel descriptor. ReadImage and ReadImageFile call it.
+// Port of 9front readimage().
+func (d *Display) ReadImageReader(r io.Reader) (*Image, error) {
+	hdr := make([]byte, 5*12)
+	if _, err := io.ReadFull(r, hdr[:11]); err != nil {
+		return nil, fmt.Errorf("readimage: header read error: %v", err)
 	}
-	if n < 5*12 {
-		return nil, fmt.Errorf("short header")
+	if string(hdr[:11]) == "compressed\n" {
+		return d.creadimage(r)
 	}
-
-	// Check for compressed marker
-	if string(header[0:11]) != "compressed\n" {
-		return nil, fmt.Errorf("not a compressed image")
+	if _, err := io.ReadFull(r, hdr[11:]); err != nil {
+		return nil, fmt.Errorf("readimage: header read error: %v", err)
+	}
+	pix, rect, isnew, err := parseImageHeader(hdr)
+	if err != nil {
+		return nil, fmt.Errorf("readimage: %v", err)
 	}
 
-	// Read the actual image header
-	_, err = io.ReadFull(f, header)
+	img, err := d.AllocImage(rect, pix, false, DNofill)
 	if err != nil {
 		return nil, err
 	}
 
-	chanstr := trimSpace(string(header[0:11]))
-	pix := strtochan(chanstr)
-	if pix == 0 {
-		return nil, fmt.Errorf("bad channel: %s", chanstr)
+	// Read and load a chunk of lines at a time, as much as fits in
+	// a load message.
+	l := bytesPerLine(rect, chantodepth(pix))
+	chunk := d.bufsize - 32
+	tmp := make([]byte, chunk)
+	for miny := rect.Min.Y; miny < rect.Max.Y; {
+		dy := rect.Max.Y - miny
+		if dy*l > chunk {
+			dy = chunk / l
+		}
+		if dy <= 0 {
+			dy = 1
+			if l > len(tmp) {
+				tmp = make([]byte, l)
+			}
+		}
+		n := dy * l
+		if _, err := io.ReadFull(r, tmp[:n]); err != nil {
+			img.Free()
+			return nil, fmt.Errorf("readimage: data read error: %v", err)
+		}
+		if !isnew {
+			// An old image: must flip all the bits.
+			for j := range tmp[:n] {
+				tmp[j] ^= 0xFF
+			}
+		}
+		if _, err := img.Load(Rect(rect.Min.X, miny, rect.Max.X, miny+dy), tmp[:n]); err != nil {
+			img.Free()
+			return nil, err
+		}
+		miny += dy
 	}
+	return img, nil
+}
 
-	minx := atoi(string(header[12:23]))
-	miny := atoi(string(header[24:35]))
-	maxx := atoi(string(header[36:47]))
-	maxy := atoi(string(header[48:59]))
-	r := Rect(minx, miny, maxx, maxy)
-
-	// Allocate image
-	img, err := d.AllocImage(r, pix, false, DTransparent)
-	if err != nil {
-		return nil, err
+// Creadimage reads a compressed image, starting with the
+// "compressed\n" line that marks one.
+func (d *Display) Creadimage(f io.Reader) (*Image, error) {
+	marker := make([]byte, 11)
+	if _, err := io.ReadFull(f, marker); err != nil {
+		return nil, fmt.Errorf("creadimage: %v", err)
+	}
+	if string(marker) != "compressed\n" {
+		return nil, fmt.Errorf("not a compressed image")
 	}
+	return d.creadimage(f)
+}
 
-	// Decompress and load
-	zr, err := zlib.NewReader(f)
+// creadimage reads a compressed image following its marker line. Each
+// block of compressed lines is passed to devdraw in a 'Y' message,
+// which decompresses it.
+// Port of 9front creadimage().
+func (d *Display) creadimage(f io.Reader) (*Image, error) {
+	hdr := make([]byte, 5*12)
+	if _, err := io.ReadFull(f, hdr); err != nil {
+		return nil, fmt.Errorf("creadimage: header read error: %v", err)
+	}
+	pix, r, isnew, err := parseImageHeader(hdr)
 	if err != nil {
-		img.Free()
-		return nil, err
+		return nil, fmt.Errorf("creadimage: %v", err)
 	}
-	defer zr.Close()
 
-	depth := chantodepth(pix)
-	bpl := bytesPerLine(r, depth)
-	data := make([]byte, bpl*r.Dy())
-	_, err = io.ReadFull(zr, data)
+	img, err := d.AllocImage(r, pix, false, 0)
 	if err != nil {
-		img.Free()
 		return nil, err
 	}
+	buf := make([]byte, CompBlockSize(r, chantodepth(pix)))
+	for miny := r.Min.Y; miny != r.Max.Y; {
+		if _, err := io.ReadFull(f, hdr[:2*12]); err != nil {
+			img.Free()
+			return nil, fmt.Errorf("creadimage: block read error: %v", err)
+		}
+		maxy := atoi(string(hdr[0:12]))
+		nb := atoi(string(hdr[12:24]))
+		if maxy <= miny || r.Max.Y < maxy {
+			img.Free()
+			return nil, fmt.Errorf("creadimage: bad maxy %d", maxy)
+		}
+		if nb <= 0 || len(buf) < nb {
+			img.Free()
+			return nil, fmt.Errorf("creadimage: bad count %d", nb)
+		}
+		if _, err := io.ReadFull(f, buf[:nb]); err != nil {
+			img.Free()
+			return nil, fmt.Errorf("creadimage: block read error: %v", err)
+		}
+		if !isnew {
+			// An old image: flip the data bits.
+			twiddlecompressed(buf[:nb])
+		}
+		if err := img.cloadblock(Rect(r.Min.X, miny, r.Max.X, maxy), buf[:nb]); err != nil {
+			img.Free()
+			return nil, fmt.Errorf("creadimage: %v", err)
+		}
+		miny = maxy
+	}
+	return img, nil
+}
 
-	_, err = img.Load(r, data)
-	if err != nil {
-		img.Free()
-		return nil, err
+// parseImageHeader parses the five 12-byte fields that begin an image
+// file: the channel descriptor and the rectangle. The first may instead
+// be the ldepth of an old image, a single digit formatted as %11d,
+// which is reported by isnew being false.
+func parseImageHeader(hdr []byte) (pix Pix, r Rectangle, isnew bool, err error) {
+	for m := 0; m < 10; m++ {
+		if hdr[m] != ' ' {
+			isnew = true
+			break
+		}
+	}
+	if hdr[11] != ' ' {
+		return 0, ZR, false, fmt.Errorf("bad format")
+	}
+	if isnew {
+		chanstr := trimSpace(string(hdr[0:11]))
+		if pix = strtochan(chanstr); pix == 0 {
+			return 0, ZR, false, fmt.Errorf("bad channel string %s", chanstr)
+		}
+	} else {
+		ldepth := int(hdr[10]) - '0'
+		if ldepth < 0 || ldepth > 3 {
+			return 0, ZR, false, fmt.Errorf("bad ldepth %d", ldepth)
+		}
+		pix = Drawld2chan[ldepth]
+	}
+	r = Rect(atoi(string(hdr[12:24])), atoi(string(hdr[24:36])),
+		atoi(string(hdr[36:48])), atoi(string(hdr[48:60])))
+	if r.Min.X > r.Max.X || r.Min.Y > r.Max.Y {
+		return 0, ZR, false, fmt.Errorf("bad rectangle")
 	}
+	return pix, r, isnew, nil
+}
 
-	return img, nil
+// twiddlecompressed inverts the literal bytes of compressed data from
+// an old image, leaving the back references alone.
+// Port of 9front _twiddlecompressed().
+func twiddlecompressed(buf []byte) {
+	for k := 0; k < len(buf); {
+		c := buf[k]
+		k++
+		if c >= 128 {
+			for j := 0; j < int(c)-128+1 && k < len(buf); j++ {
+				buf[k] ^= 0xFF
+				k++
+			}
+		} else {
+			k++
+		}
+	}
 }
 
 // ReadNImage reads n bytes of image data from a reader.
@@ -0,0 +1,213 @@
+package draw
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// testPixels returns image data for r at the given depth that mixes
+// repeated runs, which compress, with noise, which does not.
+func testPixels(r Rectangle, depth int) []byte {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]byte, bytesPerLine(r, depth)*r.Dy())
+	for k := range data {
+		switch (k / 50) % 3 {
+		case 0:
+			data[k] = byte(k / 50)
+		case 1:
+			data[k] = byte(k % 7)
+		default:
+			data[k] = byte(rng.Intn(256))
+		}
+	}
+	return data
+}
+
+// loadTestImage allocates an image on d holding testPixels.
+func loadTestImage(t *testing.T, d *Display, r Rectangle, pix Pix) (*Image, []byte) {
+	t.Helper()
+	i, err := d.AllocImage(r, pix, false, DWhite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := testPixels(r, i.Depth)
+	if _, err := i.Load(r, data); err != nil {
+		t.Fatal(err)
+	}
+	return i, data
+}
+
+// checkPixels compares the contents of i with data.
+func checkPixels(t *testing.T, i *Image, data []byte) {
+	t.Helper()
+	got := make([]byte, len(data))
+	if _, err := i.Unload(i.R, got); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := unpackRGBA(i.Pix, i.R, data)
+	have, _ := unpackRGBA(i.Pix, i.R, got)
+	if !bytes.Equal(have.Pix, want.Pix) {
+		t.Error("pixels differ")
+	}
+}
+
+func TestCompressLines(t *testing.T) {
+	for _, r := range []Rectangle{Rect(0, 0, 1, 1), Rect(0, 0, 3, 40), Rect(5, 7, 300, 90)} {
+		data := testPixels(r, 24)
+		bpl := bytesPerLine(r, 24)
+		m := newmemimage(RGB24, r, r, false, DWhite)
+		miny := r.Min.Y
+		total := 0
+		err := compressLines(data, bpl, r.Min.Y, CompBlockSize(r, 24), func(maxy int, block []byte) error {
+			if len(block) > CompBlockSize(r, 24) {
+				t.Errorf("block of %d bytes", len(block))
+			}
+			br := Rect(r.Min.X, miny, r.Max.X, maxy)
+			n, err := m.cload(br, block)
+			if err != nil {
+				return err
+			}
+			if n != len(block) {
+				t.Errorf("block of %d bytes decoded from %d", len(block), n)
+			}
+			miny = maxy
+			total += len(block)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%v: %v", r, err)
+		}
+		if miny != r.Max.Y {
+			t.Fatalf("%v: blocks end at %d", r, miny)
+		}
+		if !bytes.Equal(m.unload(r), data) {
+			t.Errorf("%v: round trip changed data", r)
+		}
+		if r.Dx() > 100 && total >= len(data) {
+			t.Errorf("%v: %d bytes compressed to %d", r, len(data), total)
+		}
+	}
+}
+
+func TestCwriteReadImage(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	for _, tt := range []struct {
+		r   Rectangle
+		pix Pix
+	}{
+		{Rect(0, 0, 37, 20), RGB24},
+		{Rect(-10, 3, 290, 203), RGB24}, // many blocks
+		{Rect(0, 0, 61, 9), GREY1},
+		{Rect(2, 2, 30, 30), CMAP8},
+	} {
+		i, data := loadTestImage(t, d, tt.r, tt.pix)
+		var buf bytes.Buffer
+		if err := i.CwriteImageWriter(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(buf.String(), "compressed\n") {
+			t.Fatal("missing compressed marker")
+		}
+		j, err := d.ReadImageReader(&buf)
+		if err != nil {
+			t.Fatalf("%v %s: %v", tt.r, chantostr(tt.pix), err)
+		}
+		if j.R != tt.r || j.Pix != tt.pix {
+			t.Errorf("read %v %s, want %v %s", j.R, chantostr(j.Pix), tt.r, chantostr(tt.pix))
+		}
+		checkPixels(t, j, data)
+		if buf.Len() != 0 {
+			t.Errorf("%d bytes left unread", buf.Len())
+		}
+	}
+}
+
+func TestReadImageUncompressed(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	i, data := loadTestImage(t, d, Rect(1, 1, 200, 100), RGB24) // many load messages
+	var buf bytes.Buffer
+	if err := i.WriteImageWriter(&buf); err != nil {
+		t.Fatal(err)
+	}
+	j, err := d.ReadImageReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPixels(t, j, data)
+}
+
+func TestReadImageOld(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	// An old ldepth 0 image holds inverted bits.
+	hdr := fmt.Sprintf("%11d %11d %11d %11d %11d ", 0, 0, 0, 8, 2)
+	j, err := d.ReadImageReader(strings.NewReader(hdr+"\xF0\x0F"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Pix != GREY1 {
+		t.Errorf("pix = %s, want k1", chantostr(j.Pix))
+	}
+	checkPixels(t, j, []byte{0x0F, 0xF0})
+
+	// The same in compressed form: one block of a literal per line.
+	c := "compressed\n" + hdr + fmt.Sprintf("%11d %11d ", 2, 4) + "\x80\xF0\x80\x0F"
+	j, err = d.ReadImageReader(strings.NewReader(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPixels(t, j, []byte{0x0F, 0xF0})
+}
+
+func TestReadImageErrors(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	for _, s := range []string{
+		"",
+		fmt.Sprintf("%11s %11d %11d %11d %11d ", "q9", 0, 0, 1, 1),
+		fmt.Sprintf("%11s %11d %11d %11d %11d ", "k8", 5, 0, 1, 1),
+		fmt.Sprintf("%11d %11d %11d %11d %11d ", 7, 0, 0, 1, 1),
+		fmt.Sprintf("%11s %11d %11d %11d %11d ", "k8", 0, 0, 4, 4) + "short",
+		"compressed\n" + fmt.Sprintf("%11s %11d %11d %11d %11d ", "k8", 0, 0, 4, 4) + fmt.Sprintf("%11d %11d ", 9, 3),
+		"compressed\n" + fmt.Sprintf("%11s %11d %11d %11d %11d ", "k8", 0, 0, 4, 4) + fmt.Sprintf("%11d %11d ", 4, 0),
+	} {
+		if _, err := d.ReadImageReader(strings.NewReader(s)); err == nil {
+			t.Errorf("ReadImageReader(%q) succeeded", s)
+		}
+	}
+	if _, err := d.Creadimage(strings.NewReader("uncompressed")); err == nil {
+		t.Error("Creadimage without marker succeeded")
+	}
+}
+
+func TestCload(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	r := Rect(0, 0, 250, 60)
+	data := testPixels(r, 8)
+	var c bytes.Buffer
+	err := compressLines(data, bytesPerLine(r, 8), r.Min.Y, CompBlockSize(r, 8), func(maxy int, block []byte) error {
+		fmt.Fprintf(&c, "%11d %11d ", maxy, len(block))
+		c.Write(block)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, _ := d.AllocImage(r, GREY8, false, DWhite)
+	n, err := i.Cload(r, c.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != c.Len() {
+		t.Errorf("Cload used %d bytes of %d", n, c.Len())
+	}
+	checkPixels(t, i, data)
+
+	if _, err := i.Cload(r, []byte(fmt.Sprintf("%11d %11d ", 0, 1))); err == nil {
+		t.Error("Cload accepted bad maxy")
+	}
+	if _, err := i.Cload(r, []byte(fmt.Sprintf("%11d %11d x", 60, 5))); err == nil {
+		t.Error("Cload accepted short block")
+	}
+}
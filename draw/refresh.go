@@ -0,0 +1,90 @@
+package draw
+
+import "io"
+
+// Refresh reports part of an image that devdraw could not restore and
+// the program must redraw, as happens to windows allocated with
+// Refmesg when they are uncovered.
+type Refresh struct {
+	ID    int       // devdraw id of the image
+	Image *Image    // the image, if it is one of the display's windows
+	R     Rectangle // the damaged rectangle
+}
+
+// refreshSize is the size of a message read from the refresh file:
+// id[4] r.min.x[4] r.min.y[4] r.max.x[4] r.max.y[4].
+const refreshSize = 5 * 4
+
+// RefreshC returns a channel on which exposure events from the
+// display's refresh file are delivered. The reader goroutine starts on
+// the first call. The channel is closed when the refresh file fails or
+// the display is closed. It is nil if the display has no refresh file,
+// so that receiving from it blocks forever.
+func (d *Display) RefreshC() <-chan Refresh {
+	if d.reffd == nil {
+		return nil
+	}
+	d.refonce.Do(func() {
+		d.refc = make(chan Refresh, 32)
+		go d.refreshproc(d.reffd, d.stopchan())
+	})
+	return d.refc
+}
+
+// refreshproc reads refresh messages and sends them on d.refc until
+// the file fails or stop is closed.
+func (d *Display) refreshproc(r io.Reader, stop <-chan struct{}) {
+	defer close(d.refc)
+
+	buf := make([]byte, 64*refreshSize)
+	n := 0
+	for {
+		m, err := r.Read(buf[n:])
+		if err != nil || m <= 0 {
+			return
+		}
+		n += m
+		k := 0
+		for ; n-k >= refreshSize; k += refreshSize {
+			a := buf[k:]
+			ref := Refresh{
+				ID: int(glong(a[0:])),
+				R: Rect(int(int32(glong(a[4:]))), int(int32(glong(a[8:]))),
+					int(int32(glong(a[12:]))), int(int32(glong(a[16:])))),
+			}
+			ref.Image = d.windowByID(ref.ID)
+			select {
+			case d.refc <- ref:
+			case <-stop:
+				return
+			}
+		}
+		n = copy(buf, buf[k:n])
+	}
+}
+
+// windowByID returns the display's window or screen image with the
+// given id, or nil.
+func (d *Display) windowByID(id int) *Image {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ScreenImage != nil && d.ScreenImage.id == id {
+		return d.ScreenImage
+	}
+	for w := d.Windows; w != nil; w = w.next {
+		if w.id == id {
+			return w
+		}
+	}
+	return nil
+}
+
+// stopchan returns the channel closed when the display is closed.
+func (d *Display) stopchan() chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop == nil {
+		d.stop = make(chan struct{})
+	}
+	return d.stop
+}
@@ -0,0 +1,71 @@
+package draw
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// refreshMsg packs a refresh file message.
+func refreshMsg(id int, r Rectangle) []byte {
+	b := make([]byte, refreshSize)
+	bplong(b[0:], uint32(id))
+	bplong(b[4:], uint32(r.Min.X))
+	bplong(b[8:], uint32(r.Min.Y))
+	bplong(b[12:], uint32(r.Max.X))
+	bplong(b[16:], uint32(r.Max.Y))
+	return b
+}
+
+func TestRefreshC(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	win := &Image{id: 7}
+	d := &Display{reffd: r, Windows: win}
+
+	c := d.RefreshC()
+	if d.RefreshC() != c {
+		t.Error("second RefreshC returned a different channel")
+	}
+
+	// Two messages in one write, the second split across writes.
+	m1 := refreshMsg(7, Rect(-5, 0, 10, 20))
+	m2 := refreshMsg(9, Rect(1, 2, 3, 4))
+	w.Write(append(m1, m2[:7]...))
+	w.Write(m2[7:])
+
+	want := []Refresh{
+		{ID: 7, Image: win, R: Rect(-5, 0, 10, 20)},
+		{ID: 9, R: Rect(1, 2, 3, 4)},
+	}
+	for _, wr := range want {
+		select {
+		case got := <-c:
+			if got != wr {
+				t.Errorf("got %+v, want %+v", got, wr)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for refresh")
+		}
+	}
+
+	d.Close()
+	select {
+	case _, ok := <-c:
+		if ok {
+			t.Error("unexpected refresh after Close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after Close")
+	}
+}
+
+func TestRefreshCNoFile(t *testing.T) {
+	d := &Display{}
+	if c := d.RefreshC(); c != nil {
+		t.Error("RefreshC without a refresh file is not nil")
+	}
+}
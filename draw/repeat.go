@@ -0,0 +1,259 @@
+package draw
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+// Default key repeat timing for keyboards opened with InitKbd.
+const (
+	DefRepeatDelay = 500 * time.Millisecond // before the first repeat
+	DefRepeatRate  = 33 * time.Millisecond  // between later repeats
+)
+
+// InitKbd opens a keyboard device speaking the 9front kbd protocol
+// and returns a Keyboardctl. If file is empty, it defaults to /dev/kbd.
+//
+// Typed characters arrive on C as for InitKeyboard. In addition, each
+// key press and release is sent on Ev, and Mod reports the modifiers
+// held. Events are dropped if Ev is not read.
+//
+// The kbd protocol reports which keys are held, so the Keyboardctl
+// repeats a held key itself, with the timing set by SetRepeat, and
+// ignores any repeats the device sends. This gives the same repeat
+// behaviour whatever the device does. Use InitKeyboard for /dev/cons.
+func InitKbd(file string) (*Keyboardctl, error) {
+	if file == "" {
+		file = "/dev/kbd"
+	}
+	fd, err := os.OpenFile(file, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("initkbd: %v", err)
+	}
+	return newKbdctl(fd), nil
+}
+
+// newKbdctl wraps an open kbd file and starts its reader.
+func newKbdctl(fd *os.File) *Keyboardctl {
+	kc := &Keyboardctl{
+		C:     make(chan rune, 20),
+		Ev:    make(chan KeyEvent, 20),
+		file:  fd,
+		done:  make(chan struct{}),
+		delay: DefRepeatDelay,
+		rate:  DefRepeatRate,
+	}
+	go kc.kbdproc(fd)
+	return kc
+}
+
+// SetRepeat sets how long a key must be held before it repeats and
+// the interval between repeats. A delay or rate of zero or less turns
+// repeating off, leaving any repeats the device sends. It only has an
+// effect on keyboards opened with InitKbd, since /dev/cons does not say
+// which keys are held.
+func (kc *Keyboardctl) SetRepeat(delay, rate time.Duration) {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	kc.delay = delay
+	kc.rate = rate
+	if !kc.repeating() {
+		kc.stoprepeat()
+	}
+}
+
+// Mod returns the modifier keys held down. It is always zero for
+// keyboards opened with InitKeyboard.
+func (kc *Keyboardctl) Mod() Mod {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	return kc.mod
+}
+
+// repeating reports whether synthesized repeats are on.
+// Called with kc.mu held.
+func (kc *Keyboardctl) repeating() bool {
+	return kc.delay > 0 && kc.rate > 0
+}
+
+// startrepeat begins repeating r after the repeat delay.
+// Called with kc.mu held.
+func (kc *Keyboardctl) startrepeat(r rune) {
+	kc.stoprepeat()
+	kc.reprune = r
+	kc.repgen++
+	gen := kc.repgen
+	kc.rep = time.AfterFunc(kc.delay, func() { kc.repeat(gen) })
+}
+
+// stoprepeat stops any repeat in progress.
+// Called with kc.mu held.
+func (kc *Keyboardctl) stoprepeat() {
+	if kc.rep != nil {
+		kc.rep.Stop()
+		kc.rep = nil
+	}
+	kc.reprune = 0
+}
+
+// repeat runs from the timer of repeat generation gen, sending the
+// held key again. A timer stopped too late may still fire after its
+// repeat has been replaced, so it does nothing unless gen is current.
+func (kc *Keyboardctl) repeat(gen uint) {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	if kc.closed || kc.rep == nil || kc.repgen != gen {
+		return
+	}
+	select {
+	case kc.C <- kc.reprune:
+	default:
+		// drop if channel full
+	}
+	kc.rep.Reset(kc.rate)
+}
+
+// send delivers a rune read from the device on kc.C.
+func (kc *Keyboardctl) send(r rune) {
+	select {
+	case kc.C <- r:
+	default:
+		// drop if channel full
+	}
+}
+
+// sendev delivers a key event on kc.Ev.
+func (kc *Keyboardctl) sendev(e KeyEvent) {
+	select {
+	case kc.Ev <- e:
+	default:
+		// drop if channel full
+	}
+}
+
+// shutdown stops repeating and closes C. It runs when the reader exits.
+func (kc *Keyboardctl) shutdown() {
+	kc.mu.Lock()
+	kc.stoprepeat()
+	kc.closed = true
+	close(kc.C)
+	if kc.Ev != nil {
+		close(kc.Ev)
+	}
+	kc.mu.Unlock()
+	close(kc.done)
+}
+
+// kbdstate is the reader's view of the keys held down.
+type kbdstate struct {
+	held  []rune // keys down according to the last k or K message
+	key   rune   // the non-modifier key most recently pressed, if held
+	fresh bool   // key was pressed since the last typed character
+}
+
+// kbdproc reads kbd messages in a goroutine. Each message is a
+// NUL-terminated string whose first byte says what it is: 'k' or 'K'
+// list the keys held after a press or release, and 'c' carries a
+// typed character.
+func (kc *Keyboardctl) kbdproc(file *os.File) {
+	defer kc.shutdown()
+
+	var st kbdstate
+	buf := make([]byte, 128)
+	var pending []byte
+	for {
+		m, err := file.Read(buf)
+		if err != nil || m <= 0 {
+			return
+		}
+		pending = append(pending, buf[:m]...)
+		for {
+			i := bytes.IndexByte(pending, 0)
+			if i < 0 {
+				break
+			}
+			kc.kbdmsg(&st, string(pending[:i]))
+			pending = pending[i+1:]
+		}
+	}
+}
+
+// kbdmsg handles one kbd message.
+func (kc *Keyboardctl) kbdmsg(st *kbdstate, msg string) {
+	if msg == "" {
+		return
+	}
+	switch msg[0] {
+	case 'k', 'K':
+		keys := []rune(msg[1:])
+		var mod Mod
+		for _, r := range keys {
+			mod |= modkeys[r]
+		}
+		kc.mu.Lock()
+		kc.mod = mod
+		kc.mu.Unlock()
+		for _, r := range st.held {
+			if !runeIn(r, keys) {
+				kc.sendev(KeyEvent{Key: r, Down: false, Mod: mod})
+			}
+		}
+		for _, r := range keys {
+			if !runeIn(r, st.held) {
+				kc.sendev(KeyEvent{Key: r, Down: true, Mod: mod})
+				if !ismodkey(r) {
+					st.key = r
+					st.fresh = true
+				}
+			}
+		}
+		st.held = keys
+		if st.key != 0 && !runeIn(st.key, keys) {
+			st.key = 0
+			st.fresh = false
+			kc.mu.Lock()
+			kc.stoprepeat()
+			kc.mu.Unlock()
+		}
+	case 'c':
+		r, _ := utf8.DecodeRuneInString(msg[1:])
+		if r == utf8.RuneError {
+			return
+		}
+		kc.mu.Lock()
+		if kc.repeating() && st.key != 0 {
+			if !st.fresh {
+				// The device's own repeat of a held key.
+				kc.mu.Unlock()
+				return
+			}
+			kc.startrepeat(r)
+		}
+		st.fresh = false
+		kc.mu.Unlock()
+		kc.send(r)
+	}
+}
+
+// ismodkey reports whether r is a modifier or lock key, which does
+// not repeat.
+func ismodkey(r rune) bool {
+	switch r {
+	case Kshift, Kctl, Kalt, Kaltgr, Kmod4, Kcaps, Knum, Kscroll:
+		return true
+	}
+	return false
+}
+
+// runeIn reports whether r is in rs.
+func runeIn(r rune, rs []rune) bool {
+	for _, x := range rs {
+		if x == r {
+			return true
+		}
+	}
+	return false
+}
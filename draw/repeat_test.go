@@ -0,0 +1,164 @@
+package draw
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// kbdpipe returns a Keyboardctl reading kbd messages from a pipe,
+// and the pipe's writer.
+func kbdpipe(t *testing.T) (*Keyboardctl, *os.File) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kc := newKbdctl(r)
+	t.Cleanup(func() {
+		w.Close()
+		kc.Close()
+	})
+	return kc, w
+}
+
+// recvRunes collects the runes arriving on kc.C within d.
+func recvRunes(kc *Keyboardctl, d time.Duration) []rune {
+	var rs []rune
+	timeout := time.After(d)
+	for {
+		select {
+		case r, ok := <-kc.C:
+			if !ok {
+				return rs
+			}
+			rs = append(rs, r)
+		case <-timeout:
+			return rs
+		}
+	}
+}
+
+func TestKbdRepeat(t *testing.T) {
+	kc, w := kbdpipe(t)
+	kc.SetRepeat(20*time.Millisecond, 5*time.Millisecond)
+
+	w.Write([]byte("ka\x00ca\x00"))
+	rs := recvRunes(kc, 60*time.Millisecond)
+	if len(rs) < 3 {
+		t.Fatalf("held key gave %q, want a and repeats", string(rs))
+	}
+	for _, r := range rs {
+		if r != 'a' {
+			t.Fatalf("held key gave %q, want only a", string(rs))
+		}
+	}
+
+	w.Write([]byte("K\x00"))
+	recvRunes(kc, 10*time.Millisecond) // a repeat may be in flight
+	if rs := recvRunes(kc, 30*time.Millisecond); len(rs) != 0 {
+		t.Errorf("released key still repeating: %q", string(rs))
+	}
+}
+
+func TestKbdStaleRepeat(t *testing.T) {
+	kc, _ := kbdpipe(t)
+	kc.SetRepeat(time.Hour, time.Hour)
+
+	// The timer for a, stopped too late, fires after b has started
+	// repeating: it must neither send b nor cut b's initial delay.
+	kc.mu.Lock()
+	kc.startrepeat('a')
+	old := kc.repgen
+	kc.startrepeat('b')
+	cur := kc.rep
+	kc.mu.Unlock()
+	kc.repeat(old)
+	if rs := recvRunes(kc, 10*time.Millisecond); len(rs) != 0 {
+		t.Errorf("stale timer sent %q", string(rs))
+	}
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	if kc.rep != cur || !cur.Stop() {
+		t.Error("stale timer disturbed the current one")
+	}
+}
+
+func TestKbdDeviceRepeatsIgnored(t *testing.T) {
+	kc, w := kbdpipe(t)
+	kc.SetRepeat(time.Hour, time.Hour)
+
+	// The device repeats b itself while it is held; then c is typed
+	// with b still down, and a shifted character arrives after a
+	// modifier press, which must not count as a new key.
+	w.Write([]byte("kb\x00cb\x00cb\x00cb\x00kbc\x00cc\x00K\x00k" + string(rune(Kshift)) + "\x00cC\x00"))
+	if rs := recvRunes(kc, 30*time.Millisecond); string(rs) != "bcC" {
+		t.Errorf("got %q, want %q", string(rs), "bcC")
+	}
+}
+
+func TestKbdRepeatOff(t *testing.T) {
+	kc, w := kbdpipe(t)
+	kc.SetRepeat(0, 0)
+
+	w.Write([]byte("kx\x00cx\x00cx\x00"))
+	if rs := recvRunes(kc, 30*time.Millisecond); string(rs) != "xx" {
+		t.Errorf("got %q, want the device's repeats %q", string(rs), "xx")
+	}
+}
+
+func TestKbdClose(t *testing.T) {
+	kc, w := kbdpipe(t)
+	w.Write([]byte("kq\x00cq\x00"))
+	if r := <-kc.C; r != 'q' {
+		t.Fatalf("got %q, want q", r)
+	}
+	kc.Close()
+	select {
+	case <-kc.Done():
+	case <-time.After(time.Second):
+		t.Fatal("reader did not exit after Close")
+	}
+	recvRunes(kc, 0)
+	if _, ok := <-kc.C; ok {
+		t.Error("C not closed")
+	}
+}
+
+func TestKbdEvents(t *testing.T) {
+	kc, w := kbdpipe(t)
+	kc.SetRepeat(0, 0)
+
+	// Ctrl down, s down, s and Ctrl up.
+	ctl := string(rune(Kctl))
+	w.Write([]byte("k" + ctl + "\x00k" + ctl + "s\x00c\x13\x00K" + ctl + "\x00K\x00"))
+	want := []KeyEvent{
+		{Kctl, true, ModCtl},
+		{'s', true, ModCtl},
+		{'s', false, ModCtl},
+		{Kctl, false, 0},
+	}
+	for _, we := range want {
+		select {
+		case e := <-kc.Ev:
+			if e != we {
+				t.Errorf("got %+v, want %+v", e, we)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no event, want %+v", we)
+		}
+	}
+	// The typed character still arrives on C.
+	if rs := recvRunes(kc, 30*time.Millisecond); string(rs) != "\x13" {
+		t.Errorf("got %q, want %q", string(rs), "\x13")
+	}
+	if m := kc.Mod(); m != 0 {
+		t.Errorf("Mod() = %v after release", m)
+	}
+
+	w.Write([]byte("k" + string(rune(Kshift)) + string(rune(Kalt)) + "\x00"))
+	<-kc.Ev
+	<-kc.Ev
+	if m := kc.Mod(); m != ModShift|ModAlt || m.String() != "Alt+Shift" {
+		t.Errorf("Mod() = %v, want Alt+Shift", m)
+	}
+}
@@ -0,0 +1,84 @@
+package draw
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// A Snarfer holds the snarf buffer, the text cut and pasted between
+// programs. Display.ReadSnarf and Display.WriteSnarf use the display's
+// Snarf field if it is set, so a port to a system without /dev/snarf
+// can supply its own clipboard.
+type Snarfer interface {
+	ReadSnarf() ([]byte, error)
+	WriteSnarf(b []byte) error
+}
+
+// FileSnarf is a Snarfer that keeps the snarf buffer in the named file,
+// such as the /dev/snarf served by rio.
+type FileSnarf string
+
+// ReadSnarf returns the contents of the file.
+func (f FileSnarf) ReadSnarf() ([]byte, error) {
+	b, err := os.ReadFile(string(f))
+	if err != nil {
+		return nil, fmt.Errorf("readsnarf: %v", err)
+	}
+	return b, nil
+}
+
+// WriteSnarf replaces the contents of the file with b.
+func (f FileSnarf) WriteSnarf(b []byte) error {
+	fd, err := os.OpenFile(string(f), os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return fmt.Errorf("writesnarf: %v", err)
+	}
+	if _, err := fd.Write(b); err != nil {
+		fd.Close()
+		return fmt.Errorf("writesnarf: %v", err)
+	}
+	return fd.Close()
+}
+
+// MemSnarf is a Snarfer that keeps the snarf buffer in memory, shared
+// only within the program. Its zero value is an empty buffer.
+type MemSnarf struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+// ReadSnarf returns a copy of the buffer.
+func (m *MemSnarf) ReadSnarf() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]byte(nil), m.buf...), nil
+}
+
+// WriteSnarf replaces the buffer with a copy of b.
+func (m *MemSnarf) WriteSnarf(b []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.buf = append(m.buf[:0], b...)
+	return nil
+}
+
+// snarfer returns where the display's snarf buffer is kept.
+func (d *Display) snarfer() Snarfer {
+	if d.Snarf != nil {
+		return d.Snarf
+	}
+	return FileSnarf(d.devpath("snarf"))
+}
+
+// ReadSnarf returns the contents of the snarf buffer. Unless the
+// display's Snarf field says otherwise, this is the file snarf in the
+// display's device directory, shared with rio and other programs.
+func (d *Display) ReadSnarf() ([]byte, error) {
+	return d.snarfer().ReadSnarf()
+}
+
+// WriteSnarf replaces the contents of the snarf buffer with b.
+func (d *Display) WriteSnarf(b []byte) error {
+	return d.snarfer().WriteSnarf(b)
+}
@@ -0,0 +1,42 @@
+package draw
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnarfFile(t *testing.T) {
+	dir := t.TempDir()
+	d := &Display{devdir: dir}
+	if _, err := d.ReadSnarf(); err == nil {
+		t.Error("ReadSnarf without a snarf file succeeded")
+	}
+
+	path := filepath.Join(dir, "snarf")
+	if err := os.WriteFile(path, []byte("a longer old snarf"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteSnarf([]byte("héllo")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "héllo" {
+		t.Errorf("snarf file = %q, want %q", b, "héllo")
+	}
+	if b, err := d.ReadSnarf(); err != nil || string(b) != "héllo" {
+		t.Errorf("ReadSnarf = %q, %v", b, err)
+	}
+}
+
+func TestSnarfMem(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	if b, err := d.ReadSnarf(); err != nil || len(b) != 0 {
+		t.Errorf("ReadSnarf = %q, %v, want empty", b, err)
+	}
+	s := []byte("text")
+	d.WriteSnarf(s)
+	s[0] = 'n'
+	if b, _ := d.ReadSnarf(); string(b) != "text" {
+		t.Errorf("ReadSnarf = %q, want text", b)
+	}
+}
@@ -126,7 +126,7 @@ func (dst *Image) stringImpl(pt Point, src *Image, sp Point, f *Font, s string,
 			// Need to load a subfont
 			sf := d.openSubfont(*subfontname)
 			if sf == nil {
-				if d.DefaultFont == nil || d.DefaultFont == f {
+				if d.DefaultFont == f {
 					break
 				}
 				f = d.DefaultFont
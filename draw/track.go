@@ -0,0 +1,206 @@
+package draw
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// ImageStats summarizes the images a display has allocated.
+type ImageStats struct {
+	Live   int   // images allocated and not yet freed
+	Bytes  int64 // pixel memory held by live images
+	Allocs int   // images allocated since Init
+	Frees  int   // images freed since Init
+}
+
+// ImageRecord describes one live image for a leak report.
+type ImageRecord struct {
+	ID    int
+	R     Rectangle
+	Pix   Pix
+	Repl  bool
+	Bytes int
+	Stack string // allocation site; empty unless built with -tags drawdebug
+}
+
+// imagetrack records the images a display has allocated.
+// It is guarded by Display.mu.
+type imagetrack struct {
+	live   map[int]*ImageRecord
+	bytes  int64
+	allocs int
+	frees  int
+}
+
+// noteAlloc records a newly allocated image.
+// Called with d.mu held.
+func (d *Display) noteAlloc(i *Image) {
+	if d.track.live == nil {
+		d.track.live = make(map[int]*ImageRecord)
+	}
+	rec := &ImageRecord{
+		ID:    i.id,
+		R:     i.R,
+		Pix:   i.Pix,
+		Repl:  i.Repl,
+		Bytes: bytesPerLine(i.R, i.Depth) * i.R.Dy(),
+	}
+	if trackStacks {
+		rec.Stack = allocStack()
+	}
+	d.track.live[i.id] = rec
+	d.track.bytes += int64(rec.Bytes)
+	d.track.allocs++
+}
+
+// noteFree forgets a freed image.
+// Called with d.mu held.
+func (d *Display) noteFree(id int) {
+	rec, ok := d.track.live[id]
+	if !ok {
+		return
+	}
+	delete(d.track.live, id)
+	d.track.bytes -= int64(rec.Bytes)
+	d.track.frees++
+}
+
+// ImageStats returns counts of the images allocated on the display.
+func (d *Display) ImageStats() ImageStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return ImageStats{
+		Live:   len(d.track.live),
+		Bytes:  d.track.bytes,
+		Allocs: d.track.allocs,
+		Frees:  d.track.frees,
+	}
+}
+
+// LiveImages returns a record for each image allocated on the display
+// and not yet freed, in order of allocation. The display's own images
+// (the screen, White, Black, font caches) are included.
+func (d *Display) LiveImages() []ImageRecord {
+	d.mu.Lock()
+	recs := make([]ImageRecord, 0, len(d.track.live))
+	for _, rec := range d.track.live {
+		recs = append(recs, *rec)
+	}
+	d.mu.Unlock()
+	sort.Slice(recs, func(i, j int) bool { return recs[i].ID < recs[j].ID })
+	return recs
+}
+
+// WriteLeakReport writes a line for each live image to w, followed by
+// its allocation stack when one was recorded.
+func (d *Display) WriteLeakReport(w io.Writer) error {
+	st := d.ImageStats()
+	_, err := fmt.Fprintf(w, "%d live images, %d bytes (%d allocated, %d freed)\n",
+		st.Live, st.Bytes, st.Allocs, st.Frees)
+	if err != nil {
+		return err
+	}
+	for _, rec := range d.LiveImages() {
+		repl := ""
+		if rec.Repl {
+			repl = " repl"
+		}
+		_, err := fmt.Fprintf(w, "image %d %v %s%s %d bytes\n",
+			rec.ID, rec.R, chantostr(rec.Pix), repl, rec.Bytes)
+		if err != nil {
+			return err
+		}
+		if rec.Stack != "" {
+			if _, err := io.WriteString(w, rec.Stack); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// allocStack returns the call stack of an image allocation,
+// omitting the frames inside this package.
+func allocStack() string {
+	pc := make([]uintptr, 32)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	s := ""
+	for {
+		f, more := frames.Next()
+		if s != "" || !inPackage(f) {
+			s += fmt.Sprintf("\t%s\n\t\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return s
+}
+
+// ImagePool recycles short-lived images of a single size and channel
+// format, such as offscreen buffers redrawn every frame, so that they
+// do not churn through devdraw image ids.
+type ImagePool struct {
+	Display *Display
+	R       Rectangle
+	Pix     Pix
+
+	mu   sync.Mutex
+	max  int
+	free []*Image
+}
+
+// NewImagePool returns a pool of images with rectangle r and channel
+// format pix that keeps at most max idle images for reuse.
+func (d *Display) NewImagePool(r Rectangle, pix Pix, max int) *ImagePool {
+	return &ImagePool{Display: d, R: r, Pix: pix, max: max}
+}
+
+// Get returns an image from the pool, allocating one if none is idle.
+// The contents of a reused image are whatever was last drawn in it;
+// its clipping rectangle is reset to R.
+func (p *ImagePool) Get() (*Image, error) {
+	p.mu.Lock()
+	if n := len(p.free); n > 0 {
+		i := p.free[n-1]
+		p.free = p.free[:n-1]
+		p.mu.Unlock()
+		if i.Repl || i.Clipr != p.R {
+			i.ReplClipr(false, p.R)
+		}
+		return i, nil
+	}
+	p.mu.Unlock()
+	return p.Display.AllocImage(p.R, p.Pix, false, DNofill)
+}
+
+// Put returns an image obtained from Get to the pool. It is freed
+// instead if the pool is full or the image does not match the pool.
+func (p *ImagePool) Put(i *Image) {
+	if i == nil || i.Display == nil {
+		return
+	}
+	p.mu.Lock()
+	if i.Display == p.Display && i.R == p.R && i.Pix == p.Pix && len(p.free) < p.max {
+		p.free = append(p.free, i)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	i.Free()
+}
+
+// Close frees the idle images held by the pool.
+func (p *ImagePool) Close() {
+	p.mu.Lock()
+	free := p.free
+	p.free = nil
+	p.mu.Unlock()
+	for _, i := range free {
+		i.Free()
+	}
+}
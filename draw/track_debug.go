@@ -0,0 +1,7 @@
+//go:build drawdebug
+
+package draw
+
+// trackStacks records the allocation site of every image, for
+// WriteLeakReport. Build with -tags drawdebug to enable it.
+const trackStacks = true
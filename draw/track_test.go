@@ -0,0 +1,97 @@
+package draw
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// bufDisplay returns a display that only buffers protocol messages.
+func bufDisplay() *Display {
+	d := &Display{bufsize: 10000}
+	d.buf = make([]byte, d.bufsize+5)
+	return d
+}
+
+func TestImageTracking(t *testing.T) {
+	d := bufDisplay()
+	a, err := d.AllocImage(Rect(0, 0, 10, 10), RGB24, false, DWhite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := d.AllocImage(Rect(0, 0, 1, 1), GREY1, true, DBlack)
+	c, _ := d.AllocImage(Rect(0, 0, 16, 2), GREY8, false, DBlack)
+
+	st := d.ImageStats()
+	if st.Live != 3 || st.Allocs != 3 || st.Frees != 0 {
+		t.Errorf("stats after alloc = %+v, want 3 live, 3 allocs", st)
+	}
+	if want := int64(30*10 + 1 + 32); st.Bytes != want {
+		t.Errorf("Bytes = %d, want %d", st.Bytes, want)
+	}
+
+	b.Free()
+	b.Free() // second Free is a no-op
+	st = d.ImageStats()
+	if st.Live != 2 || st.Frees != 1 || st.Bytes != 300+32 {
+		t.Errorf("stats after free = %+v, want 2 live, 1 free, 332 bytes", st)
+	}
+
+	recs := d.LiveImages()
+	if len(recs) != 2 || recs[0].ID != a.id || recs[1].ID != c.id {
+		t.Fatalf("LiveImages = %+v, want images %d and %d", recs, a.id, c.id)
+	}
+	if recs[1].R != c.R || recs[1].Pix != GREY8 {
+		t.Errorf("record = %+v, want %v k8", recs[1], c.R)
+	}
+
+	var buf bytes.Buffer
+	if err := d.WriteLeakReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "2 live images, 332 bytes (3 allocated, 1 freed)\n") {
+		t.Errorf("report header wrong:\n%s", out)
+	}
+	if !strings.Contains(out, "image 3 ") || !strings.Contains(out, " k8 ") {
+		t.Errorf("report missing image 3:\n%s", out)
+	}
+}
+
+func TestImagePool(t *testing.T) {
+	d := bufDisplay()
+	r := Rect(0, 0, 8, 8)
+	p := d.NewImagePool(r, RGBA32, 1)
+
+	a, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := p.Get()
+	if a == b {
+		t.Fatal("Get returned the same image twice")
+	}
+	p.Put(a)
+	p.Put(b) // pool is full: freed
+	if b.Display != nil {
+		t.Error("image beyond pool size not freed")
+	}
+	if st := d.ImageStats(); st.Live != 1 {
+		t.Errorf("live = %d, want 1", st.Live)
+	}
+
+	if c, _ := p.Get(); c != a {
+		t.Error("Get did not reuse the idle image")
+	}
+	other, _ := d.AllocImage(Rect(0, 0, 4, 4), RGBA32, false, DNofill)
+	p.Put(other) // wrong size: freed
+	if other.Display != nil {
+		t.Error("mismatched image kept by pool")
+	}
+
+	p.Put(a)
+	p.Close()
+	if a.Display != nil || d.ImageStats().Live != 0 {
+		t.Error("Close did not free idle images")
+	}
+}
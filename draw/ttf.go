@@ -0,0 +1,572 @@
+package draw
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// A ttfont is a parsed TrueType font at one size. Its glyphs are
+// rasterized into subfonts of 256 characters as loadchar asks for
+// them, so a font with thousands of glyphs costs little until they
+// are used.
+type ttfont struct {
+	name     string
+	size     int     // pixels per em
+	scale    float64 // pixels per font unit
+	ascent   int     // pixels
+	height   int     // pixels
+	nglyph   int
+	nhmetric int
+	localong bool
+	cmap     map[rune]int
+	glyf     []byte
+	loca     []byte
+	hmtx     []byte
+}
+
+// OpenTTF opens a TrueType font file and returns a Font that draws it
+// at size pixels to the em, scaled by ScaleSize for the display's DPI.
+// Glyphs are rendered with antialiasing into 8-bit subfonts when first
+// drawn. Fonts with PostScript (CFF) outlines are not supported.
+func (d *Display) OpenTTF(path string, size int) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return d.BuildTTF(data, path, size)
+}
+
+// BuildTTF is like OpenTTF but takes the contents of the font file.
+// The name identifies the font's subfonts in the subfont cache.
+func (d *Display) BuildTTF(data []byte, name string, size int) (*Font, error) {
+	if d != nil {
+		size = d.ScaleSize(size)
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("openttf: bad size %d", size)
+	}
+	t, err := parsettf(data, name, size)
+	if err != nil {
+		return nil, fmt.Errorf("openttf: %s: %v", name, err)
+	}
+
+	fnt := &Font{
+		Display: d,
+		Name:    name,
+		Height:  t.height,
+		Ascent:  t.ascent,
+		ncache:  NFCACHE + NFLOOK,
+		nsubf:   NFSUBF,
+		age:     1,
+		ttf:     t,
+	}
+	fnt.cache = make([]Cacheinfo, fnt.ncache)
+	fnt.subf = make([]Cachesubf, fnt.nsubf)
+
+	// One range per block of 256 characters holding any glyphs. The
+	// first block is always present: character 0 draws the font's
+	// missing-glyph shape, for loadchar to substitute.
+	blocks := map[int]bool{0: true}
+	for r := range t.cmap {
+		blocks[int(r)>>8] = true
+	}
+	var bs []int
+	for b := range blocks {
+		bs = append(bs, b)
+	}
+	sort.Ints(bs)
+	for _, b := range bs {
+		fnt.sub = append(fnt.sub, &Cachefont{
+			Min:         b << 8,
+			Max:         b<<8 | 0xFF,
+			Name:        name,
+			Subfontname: fmt.Sprintf("%s.%d.%04x", name, size, b<<8),
+		})
+	}
+	fnt.nsub = len(fnt.sub)
+	return fnt, nil
+}
+
+// parsettf reads the tables of a TrueType font needed to draw it.
+func parsettf(data []byte, name string, size int) (*ttfont, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("short file")
+	}
+	// A collection holds several fonts; use the first. Table offsets
+	// are from the start of the file in either case.
+	base := 0
+	if string(data[:4]) == "ttcf" {
+		base = u32(data, 12)
+		if base > len(data)-12 {
+			return nil, fmt.Errorf("bad collection")
+		}
+	}
+	switch string(data[base : base+4]) {
+	case "\x00\x01\x00\x00", "true":
+	case "OTTO":
+		return nil, fmt.Errorf("CFF outlines not supported")
+	default:
+		return nil, fmt.Errorf("not a TrueType font")
+	}
+
+	tables := make(map[string][]byte)
+	ntab := u16(data, base+4)
+	for i := 0; i < ntab; i++ {
+		rec := base + 12 + 16*i
+		off, n := u32(data, rec+8), u32(data, rec+12)
+		if rec+16 > len(data) || off > len(data) || n > len(data)-off {
+			return nil, fmt.Errorf("bad table directory")
+		}
+		tables[string(data[rec:rec+4])] = data[off : off+n]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "cmap", "loca", "glyf"} {
+		if tables[tag] == nil {
+			return nil, fmt.Errorf("no %s table", tag)
+		}
+	}
+
+	head, hhea := tables["head"], tables["hhea"]
+	upem := u16(head, 18)
+	if upem == 0 {
+		return nil, fmt.Errorf("bad units per em")
+	}
+	t := &ttfont{
+		name:     name,
+		size:     size,
+		scale:    float64(size) / float64(upem),
+		nglyph:   u16(tables["maxp"], 4),
+		nhmetric: u16(hhea, 34),
+		localong: u16(head, 50) != 0,
+		glyf:     tables["glyf"],
+		loca:     tables["loca"],
+		hmtx:     tables["hmtx"],
+	}
+	if t.nhmetric == 0 || len(t.hmtx) < 4*t.nhmetric {
+		return nil, fmt.Errorf("bad hmtx table")
+	}
+	ascent := int(math.Ceil(float64(s16(hhea, 4)) * t.scale))
+	descent := int(math.Ceil(float64(-s16(hhea, 6)) * t.scale))
+	gap := int(math.Floor(float64(s16(hhea, 8))*t.scale + 0.5))
+	// Guard against nonsense metrics, and keep within the byte-sized
+	// fields of Fontchar.
+	t.ascent = clampint(ascent, 1, 2*size)
+	t.height = t.ascent + clampint(descent, 0, size) + clampint(gap, 0, size)
+	if t.height > 255 {
+		return nil, fmt.Errorf("size %d too large", size)
+	}
+
+	var err error
+	if t.cmap, err = parsecmap(tables["cmap"], t.nglyph); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// parsecmap reads the character to glyph mapping, preferring a full
+// Unicode (format 12) subtable to a 16-bit (format 4) one.
+func parsecmap(b []byte, nglyph int) (map[rune]int, error) {
+	var sub4, sub12 []byte
+	for i := 0; i < u16(b, 2); i++ {
+		rec := 4 + 8*i
+		pid, eid, off := u16(b, rec), u16(b, rec+2), u32(b, rec+4)
+		if off >= len(b) {
+			continue
+		}
+		if pid != 0 && !(pid == 3 && (eid == 1 || eid == 10)) {
+			continue
+		}
+		switch u16(b, off) {
+		case 4:
+			sub4 = b[off:]
+		case 12:
+			sub12 = b[off:]
+		}
+	}
+	m := make(map[rune]int)
+	add := func(r rune, g int) {
+		if g > 0 && g < nglyph && r > 0 && r <= 0x10FFFF {
+			m[r] = g
+		}
+	}
+	// Ranges may overlap, so bound the work by the number of runes a
+	// sound table could map rather than trusting their sum.
+	budget := 0x110000
+	spend := func(n int) bool {
+		budget -= n
+		return budget >= 0
+	}
+	switch {
+	case sub12 != nil:
+		n := u32(sub12, 12)
+		if n > (len(sub12)-16)/12 {
+			return nil, fmt.Errorf("bad cmap table")
+		}
+		for i := 0; i < n; i++ {
+			g := 16 + 12*i
+			lo, hi, gid := u32(sub12, g), u32(sub12, g+4), u32(sub12, g+8)
+			if hi > 0x10FFFF || lo > hi || gid >= nglyph {
+				continue
+			}
+			// Runes past the last glyph map to nothing.
+			hi = min(hi, lo+nglyph-1-gid)
+			if !spend(hi - lo + 1) {
+				return nil, fmt.Errorf("bad cmap table")
+			}
+			for r := lo; r <= hi; r++ {
+				add(rune(r), gid+r-lo)
+			}
+		}
+	case sub4 != nil:
+		nseg := u16(sub4, 6) / 2
+		for i := 0; i < nseg; i++ {
+			end := min(u16(sub4, 14+2*i), 0xFFFE)
+			start := u16(sub4, 16+2*nseg+2*i)
+			delta := u16(sub4, 16+4*nseg+2*i)
+			roff := 16 + 6*nseg + 2*i
+			ro := u16(sub4, roff)
+			if start > end {
+				continue
+			}
+			if !spend(end - start + 1) {
+				return nil, fmt.Errorf("bad cmap table")
+			}
+			for c := start; c <= end; c++ {
+				if ro == 0 {
+					add(rune(c), (c+delta)&0xFFFF)
+				} else if g := u16(sub4, roff+ro+2*(c-start)); g != 0 {
+					add(rune(c), (g+delta)&0xFFFF)
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("no Unicode cmap")
+	}
+	return m, nil
+}
+
+// ttpoint is a point of a glyph outline, in font units with y up.
+type ttpoint struct {
+	x, y float64
+	on   bool // on the curve, not a control point
+}
+
+// advance returns the advance width of glyph g in font units.
+func (t *ttfont) advance(g int) int {
+	if g >= t.nhmetric {
+		g = t.nhmetric - 1
+	}
+	return u16(t.hmtx, 4*g)
+}
+
+// outline returns the contours of glyph g, following components of
+// composite glyphs to at most depth levels.
+func (t *ttfont) outline(g, depth int) [][]ttpoint {
+	if g < 0 || g >= t.nglyph || depth < 0 {
+		return nil
+	}
+	var off, end int
+	if t.localong {
+		off, end = u32(t.loca, 4*g), u32(t.loca, 4*g+4)
+	} else {
+		off, end = 2*u16(t.loca, 2*g), 2*u16(t.loca, 2*g+2)
+	}
+	if off >= end || end > len(t.glyf) {
+		return nil
+	}
+	b := t.glyf[off:end]
+	ncont := s16(b, 0)
+	if ncont < 0 {
+		return t.compound(b, depth)
+	}
+
+	// A simple glyph: end points, instructions, then flags and
+	// coordinates in compressed form.
+	p := 10 + 2*ncont
+	npt := 0
+	if ncont > 0 {
+		npt = u16(b, p-2) + 1
+	}
+	p += 2 + u16(b, p)
+	flags := make([]byte, 0, npt)
+	for len(flags) < npt && p < len(b) {
+		f := b[p]
+		p++
+		flags = append(flags, f)
+		if f&8 != 0 && p < len(b) {
+			for n := b[p]; n > 0 && len(flags) < npt; n-- {
+				flags = append(flags, f)
+			}
+			p++
+		}
+	}
+	if len(flags) < npt {
+		return nil
+	}
+	pts := make([]ttpoint, npt)
+	coord := func(short, same byte, set func(i int, v float64)) {
+		v := 0
+		for i, f := range flags {
+			switch {
+			case f&short != 0:
+				d := int(u8(b, p))
+				p++
+				if f&same == 0 {
+					d = -d
+				}
+				v += d
+			case f&same == 0:
+				v += s16(b, p)
+				p += 2
+			}
+			set(i, float64(v))
+		}
+	}
+	coord(2, 16, func(i int, v float64) { pts[i].x = v })
+	coord(4, 32, func(i int, v float64) { pts[i].y = v })
+	for i, f := range flags {
+		pts[i].on = f&1 != 0
+	}
+
+	var conts [][]ttpoint
+	start := 0
+	for i := 0; i < ncont; i++ {
+		e := u16(b, 10+2*i) + 1
+		if e <= start || e > npt {
+			break
+		}
+		conts = append(conts, pts[start:e])
+		start = e
+	}
+	return conts
+}
+
+// compound returns the contours of a composite glyph: its components,
+// each moved and transformed as the glyph says.
+func (t *ttfont) compound(b []byte, depth int) [][]ttpoint {
+	const (
+		argWords  = 0x0001
+		argsXY    = 0x0002
+		haveScale = 0x0008
+		more      = 0x0020
+		scaleXY   = 0x0040
+		twoByTwo  = 0x0080
+	)
+	var conts [][]ttpoint
+	p := 10
+	for {
+		flags, g := u16(b, p), u16(b, p+2)
+		p += 4
+		var dx, dy int
+		if flags&argWords != 0 {
+			dx, dy = s16(b, p), s16(b, p+2)
+			p += 4
+		} else {
+			dx, dy = int(int8(u8(b, p))), int(int8(u8(b, p+1)))
+			p += 2
+		}
+		if flags&argsXY == 0 {
+			// Components placed by matching points are rare;
+			// leave them unmoved.
+			dx, dy = 0, 0
+		}
+		a, bb, c, d := 1.0, 0.0, 0.0, 1.0
+		f2dot14 := func(i int) float64 { return float64(s16(b, i)) / (1 << 14) }
+		switch {
+		case flags&haveScale != 0:
+			a = f2dot14(p)
+			d = a
+			p += 2
+		case flags&scaleXY != 0:
+			a, d = f2dot14(p), f2dot14(p+2)
+			p += 4
+		case flags&twoByTwo != 0:
+			a, bb, c, d = f2dot14(p), f2dot14(p+2), f2dot14(p+4), f2dot14(p+6)
+			p += 8
+		}
+		for _, cont := range t.outline(g, depth-1) {
+			nc := make([]ttpoint, len(cont))
+			for i, pt := range cont {
+				nc[i] = ttpoint{
+					x:  a*pt.x + c*pt.y + float64(dx),
+					y:  bb*pt.x + d*pt.y + float64(dy),
+					on: pt.on,
+				}
+			}
+			conts = append(conts, nc)
+		}
+		if flags&more == 0 || p >= len(b) {
+			return conts
+		}
+	}
+}
+
+// glyph rasterizes glyph g. It returns the coverage of each pixel of
+// the glyph's bounding box, which is w by h pixels with its top left
+// corner at (x, y) relative to the origin on the baseline, y down.
+func (t *ttfont) glyph(g int) (pix []byte, x, y, w, h int) {
+	conts := t.outline(g, 8)
+	xmin, ymin := math.Inf(1), math.Inf(1)
+	xmax, ymax := math.Inf(-1), math.Inf(-1)
+	for _, c := range conts {
+		for _, p := range c {
+			xmin, xmax = math.Min(xmin, p.x), math.Max(xmax, p.x)
+			ymin, ymax = math.Min(ymin, p.y), math.Max(ymax, p.y)
+		}
+	}
+	if xmin > xmax {
+		return nil, 0, 0, 0, 0
+	}
+	x = int(math.Floor(xmin * t.scale))
+	y = -int(math.Ceil(ymax * t.scale))
+	w = int(math.Ceil(xmax*t.scale)) - x
+	h = -int(math.Floor(ymin*t.scale)) - y
+	if w <= 0 || h <= 0 || w > 4*t.size || h > 4*t.size {
+		// Empty, or too big to be sensible.
+		return nil, 0, 0, 0, 0
+	}
+
+	r := newttraster(w, h)
+	tr := func(p ttpoint) (float64, float64) {
+		return p.x*t.scale - float64(x), -p.y*t.scale - float64(y)
+	}
+	for _, c := range conts {
+		if len(c) < 2 {
+			continue
+		}
+		// Start from an on-curve point, or the midpoint of two
+		// control points if there is none.
+		s := 0
+		for s < len(c) && !c[s].on {
+			s++
+		}
+		var sx, sy float64
+		if s == len(c) {
+			sx, sy = tr(ttpoint{x: (c[0].x + c[1].x) / 2, y: (c[0].y + c[1].y) / 2})
+		} else {
+			sx, sy = tr(c[s])
+		}
+		px, py := sx, sy
+		var cx, cy float64
+		ctl := false
+		for i := 1; i <= len(c); i++ {
+			p := c[(s+i)%len(c)]
+			qx, qy := tr(p)
+			switch {
+			case p.on && ctl:
+				r.quad(px, py, cx, cy, qx, qy)
+				px, py, ctl = qx, qy, false
+			case p.on:
+				r.line(px, py, qx, qy)
+				px, py = qx, qy
+			case ctl:
+				// Two control points in a row imply an
+				// on-curve point between them.
+				mx, my := (cx+qx)/2, (cy+qy)/2
+				r.quad(px, py, cx, cy, mx, my)
+				px, py = mx, my
+				cx, cy = qx, qy
+			default:
+				cx, cy, ctl = qx, qy, true
+			}
+		}
+		if ctl {
+			r.quad(px, py, cx, cy, sx, sy)
+		} else if px != sx || py != sy {
+			r.line(px, py, sx, sy)
+		}
+	}
+	return r.coverage(), x, y, w, h
+}
+
+// subfont renders the characters of cf into a subfont on d.
+func (t *ttfont) subfont(d *Display, cf *Cachefont) *Subfont {
+	n := cf.Max - cf.Min + 1
+	type rendered struct {
+		pix        []byte
+		x, y, w, h int
+	}
+	glyphs := make([]rendered, n)
+	info := make([]Fontchar, n+1)
+	width := 0
+	for i := 0; i < n; i++ {
+		// Characters without glyphs take no space.
+		info[i].X = width
+		r := rune(cf.Min + i)
+		g, ok := t.cmap[r]
+		if !ok && r != PJW {
+			continue
+		}
+		var gl rendered
+		gl.pix, gl.x, gl.y, gl.w, gl.h = t.glyph(g)
+		glyphs[i] = gl
+		adv := int(math.Floor(float64(t.advance(g))*t.scale + 0.5))
+		fc := &info[i]
+		fc.Width = byte(clampint(adv, 1, 255))
+		fc.Left = int8(clampint(gl.x, -128, 127))
+		fc.Top = byte(clampint(t.ascent+gl.y, 0, t.height))
+		fc.Bottom = byte(clampint(t.ascent+gl.y+gl.h, 0, t.height))
+		width += gl.w
+	}
+	info[n].X = width
+
+	img, err := d.AllocImage(Rect(0, 0, max(width, 1), t.height), GREY8, false, DBlack)
+	if err != nil {
+		return nil
+	}
+	bits := make([]byte, max(width, 1)*t.height)
+	for i, gl := range glyphs {
+		for row := 0; row < gl.h; row++ {
+			y := t.ascent + gl.y + row
+			if y < 0 || y >= t.height {
+				continue
+			}
+			copy(bits[y*img.R.Dx()+info[i].X:], gl.pix[row*gl.w:(row+1)*gl.w])
+		}
+	}
+	if _, err := img.Load(img.R, bits); err != nil {
+		img.Free()
+		return nil
+	}
+	return AllocSubfont(cf.Subfontname, n, t.height, t.ascent, info, img)
+}
+
+// clampint returns v limited to [lo, hi].
+func clampint(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// Big-endian field readers for font tables. Reads past the end of b
+// return zero, so that a damaged font draws badly rather than failing.
+
+func u8(b []byte, i int) byte {
+	if i < 0 || i >= len(b) {
+		return 0
+	}
+	return b[i]
+}
+
+func u16(b []byte, i int) int {
+	if i < 0 || i+2 > len(b) {
+		return 0
+	}
+	return int(b[i])<<8 | int(b[i+1])
+}
+
+func s16(b []byte, i int) int {
+	return int(int16(u16(b, i)))
+}
+
+func u32(b []byte, i int) int {
+	if i < 0 || i+4 > len(b) {
+		return 0
+	}
+	return int(uint32(b[i])<<24 | uint32(b[i+1])<<16 | uint32(b[i+2])<<8 | uint32(b[i+3]))
+}
@@ -0,0 +1,425 @@
+package draw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// ttglyph describes a glyph for buildttf: either simple contours of
+// (x, y, on) points or a single component.
+type ttglyph struct {
+	adv   int
+	conts [][][3]int
+	comp  *ttcomp
+}
+
+type ttcomp struct {
+	g, dx, dy int
+	scale     float64
+}
+
+// buildttf returns a minimal TrueType font with 100 units to the em,
+// an ascender of 80 and a descender of -20, holding the given glyphs
+// and mapping the given characters to them.
+func buildttf(glyphs []ttglyph, cmap map[rune]int) []byte {
+	be := binary.BigEndian
+	w := func(b *bytes.Buffer, vs ...int) {
+		for _, v := range vs {
+			binary.Write(b, be, int16(v))
+		}
+	}
+
+	var glyf, loca, hmtx bytes.Buffer
+	for _, g := range glyphs {
+		binary.Write(&loca, be, uint32(glyf.Len()))
+		w(&hmtx, g.adv, 0)
+		switch {
+		case g.comp != nil:
+			w(&glyf, -1, 0, 0, 0, 0)
+			w(&glyf, 0x0001|0x0002|0x0008, g.comp.g, g.comp.dx, g.comp.dy, int(g.comp.scale*(1<<14)))
+		case g.conts != nil:
+			w(&glyf, len(g.conts), 0, 0, 0, 0)
+			n := 0
+			for _, c := range g.conts {
+				n += len(c)
+				w(&glyf, n-1)
+			}
+			w(&glyf, 0) // no instructions
+			for _, c := range g.conts {
+				for _, p := range c {
+					glyf.WriteByte(byte(p[2]))
+				}
+			}
+			for k := 0; k < 2; k++ {
+				last := 0
+				for _, c := range g.conts {
+					for _, p := range c {
+						w(&glyf, p[k]-last)
+						last = p[k]
+					}
+				}
+			}
+		}
+	}
+	binary.Write(&loca, be, uint32(glyf.Len()))
+
+	// A format 4 cmap with a segment per character.
+	var rs []int
+	for r := range cmap {
+		rs = append(rs, int(r))
+	}
+	for i := range rs {
+		for j := i + 1; j < len(rs); j++ {
+			if rs[j] < rs[i] {
+				rs[i], rs[j] = rs[j], rs[i]
+			}
+		}
+	}
+	rs = append(rs, 0xFFFF)
+	var cm bytes.Buffer
+	w(&cm, 0, 1, 3, 1, 0, 12)
+	w(&cm, 4, 16+8*len(rs), 0, 2*len(rs), 0, 0, 0)
+	for _, r := range rs {
+		w(&cm, r)
+	}
+	w(&cm, 0)
+	for _, r := range rs {
+		w(&cm, r)
+	}
+	for _, r := range rs {
+		w(&cm, cmap[rune(r)]-r)
+	}
+	for range rs {
+		w(&cm, 0)
+	}
+
+	var head, hhea, maxp bytes.Buffer
+	w(&head, 1, 0, 1, 0, 0, 0, 0x5F0F, 0x3CF5, 0, 100)
+	w(&head, make([]int, 15)...)
+	w(&head, 1, 0) // long loca
+	w(&hhea, 1, 0, 80, -20, 0)
+	w(&hhea, make([]int, 12)...)
+	w(&hhea, len(glyphs))
+	w(&maxp, 0, 0x5000, len(glyphs))
+
+	tables := []struct {
+		tag  string
+		data []byte
+	}{
+		{"cmap", cm.Bytes()}, {"glyf", glyf.Bytes()}, {"head", head.Bytes()},
+		{"hhea", hhea.Bytes()}, {"hmtx", hmtx.Bytes()}, {"loca", loca.Bytes()},
+		{"maxp", maxp.Bytes()},
+	}
+	var f bytes.Buffer
+	w(&f, 1, 0, len(tables), 0, 0, 0)
+	off := 12 + 16*len(tables)
+	for _, t := range tables {
+		f.WriteString(t.tag)
+		binary.Write(&f, be, uint32(0))
+		binary.Write(&f, be, uint32(off))
+		binary.Write(&f, be, uint32(len(t.data)))
+		off += len(t.data)
+	}
+	for _, t := range tables {
+		f.Write(t.data)
+	}
+	return f.Bytes()
+}
+
+// square returns a contour for the square with corners (x0, y0) and
+// (x1, y1), clockwise as TrueType expects.
+func square(x0, y0, x1, y1 int) [][3]int {
+	return [][3]int{{x0, y0, 1}, {x0, y1, 1}, {x1, y1, 1}, {x1, y0, 1}}
+}
+
+// testttf is a font with a missing-glyph box, A (a square), B (a
+// smaller A, as a component), space, and O (a circle drawn with
+// control points alone).
+func testttf() []byte {
+	return buildttf(testttfglyphs(), map[rune]int{'A': 1, 'B': 2, ' ': 3, 'O': 4})
+}
+
+// testttfglyphs returns the glyphs of testttf.
+func testttfglyphs() []ttglyph {
+	// Control points at the corners of the octagon around a circle of
+	// radius 50, so the implied on-curve points touch the circle.
+	var circle [][3]int
+	R := 50 / math.Cos(math.Pi/8)
+	for i := 0; i < 8; i++ {
+		a := -float64(i)*math.Pi/4 + math.Pi/8
+		circle = append(circle, [3]int{50 + int(math.Round(R*math.Cos(a))), 50 + int(math.Round(R*math.Sin(a))), 0})
+	}
+	return []ttglyph{
+		{adv: 50, conts: [][][3]int{square(0, 0, 40, 60)}},
+		{adv: 60, conts: [][][3]int{square(0, 0, 50, 50)}},
+		{adv: 60, comp: &ttcomp{g: 1, dx: 10, dy: 0, scale: 0.5}},
+		{adv: 25},
+		{adv: 100, conts: [][][3]int{circle}},
+	}
+}
+
+func TestTTRaster(t *testing.T) {
+	// A square on half pixels: full inside, half on the edges and a
+	// quarter at the corners, whichever way round it is drawn.
+	for _, dir := range []float64{1, -1} {
+		r := newttraster(8, 8)
+		pts := [][2]float64{{1.5, 1.5}, {5.5, 1.5}, {5.5, 5.5}, {1.5, 5.5}}
+		for i := range pts {
+			p, q := pts[i], pts[(i+1)%4]
+			if dir < 0 {
+				p, q = q, p
+			}
+			r.line(p[0], p[1], q[0], q[1])
+		}
+		c := r.coverage()
+		for _, tt := range []struct{ x, y, want int }{
+			{3, 3, 255}, {1, 3, 128}, {5, 3, 128}, {3, 1, 128}, {1, 1, 64}, {5, 5, 64}, {0, 0, 0}, {6, 3, 0},
+		} {
+			if got := int(c[tt.y*8+tt.x]); got != tt.want {
+				t.Errorf("dir %v: coverage at %d,%d = %d, want %d", dir, tt.x, tt.y, got, tt.want)
+			}
+		}
+	}
+
+	// A diagonal line across pixels: a triangle of area 18.
+	r := newttraster(8, 8)
+	r.line(1, 1, 7, 7)
+	r.line(7, 7, 1, 7)
+	r.line(1, 7, 1, 1)
+	sum := 0
+	for _, v := range r.coverage() {
+		sum += int(v)
+	}
+	if a := float64(sum) / 255; math.Abs(a-18) > 0.1 {
+		t.Errorf("triangle area = %.2f, want 18", a)
+	}
+}
+
+func TestOpenTTF(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	f, err := d.BuildTTF(testttf(), "test.ttf", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0.2 pixels to the unit.
+	if f.Height != 20 || f.Ascent != 16 {
+		t.Errorf("height %d ascent %d, want 20 16", f.Height, f.Ascent)
+	}
+	if w := f.StringWidth("AB O"); w != 12+12+5+20 {
+		t.Errorf("StringWidth = %d, want 49", w)
+	}
+	if w := f.StringWidth("一"); w != 10 {
+		t.Errorf("missing character has width %d, want that of the missing glyph, 10", w)
+	}
+
+	d.Image.String(Pt(0, 0), d.Black, ZP, f, "AB O")
+	d.Flush()
+	const black, white = 0x000000FF, 0xFFFFFFFF
+	// A fills x 0-10, rows 6-16; B is half the size, 2 pixels in.
+	if n := memcount(d, Rect(0, 6, 10, 16), black); n != 100 {
+		t.Errorf("A has %d black pixels, want 100", n)
+	}
+	if n := memcount(d, Rect(0, 0, 12, 20), black); n != 100 {
+		t.Errorf("A's cell has %d black pixels, want 100", n)
+	}
+	if n := memcount(d, Rect(12+2, 11, 12+7, 16), black); n != 25 {
+		t.Errorf("B has %d black pixels, want 25", n)
+	}
+	if n := memcount(d, Rect(12, 0, 24, 20), white); n != 12*20-25 {
+		t.Errorf("B's cell has %d white pixels, want %d", n, 12*20-25)
+	}
+	// The circle is smooth: its middle is black, its corners white,
+	// and there is grey at its edge.
+	ox := 29
+	if memcolor(d, ox+10, 6) != black || memcolor(d, ox, 15) != white {
+		t.Error("circle drawn wrongly")
+	}
+	grey := 0
+	for x := ox; x < ox+20; x++ {
+		if c := memcolor(d, x, 1); c != black && c != white {
+			grey++
+		}
+	}
+	if grey == 0 {
+		t.Error("circle not antialiased")
+	}
+}
+
+func TestOpenTTFErrors(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	font := testttf()
+	for _, tt := range []struct {
+		name string
+		data []byte
+		size int
+	}{
+		{"empty", nil, 10},
+		{"garbage", []byte("this is not a font file at all"), 10},
+		{"cff", append([]byte("OTTO"), font[4:]...), 10},
+		{"size", font, 0},
+		{"huge", font, 300},
+		{"truncated", font[:40], 10},
+	} {
+		if _, err := d.BuildTTF(tt.data, tt.name, tt.size); err == nil {
+			t.Errorf("%s: BuildTTF succeeded", tt.name)
+		}
+	}
+	if _, err := d.OpenTTF("/nonexistent.ttf", 10); err == nil {
+		t.Error("OpenTTF of a missing file succeeded")
+	}
+}
+
+func TestFontFallback(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	glyphs := testttfglyphs()
+	f, err := d.BuildTTF(buildttf(glyphs, map[rune]int{'A': 1}), "latin.ttf", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fb, err := d.BuildTTF(buildttf(glyphs, map[rune]int{'B': 2, '一': 4}), "cjk.ttf", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := f.StringWidth("AB一"); w != 12+10+10 {
+		t.Errorf("StringWidth without fallback = %d, want 32", w)
+	}
+	if err := f.AddFallback(fb); err != nil {
+		t.Fatal(err)
+	}
+	// A from f, B and 一 from fb; the missing glyph still from f.
+	if w := f.StringWidth("AB一"); w != 12+12+20 {
+		t.Errorf("StringWidth = %d, want 44", w)
+	}
+	if w := f.StringWidth("C"); w != 10 {
+		t.Errorf("missing character has width %d, want 10", w)
+	}
+
+	d.Image.String(Pt(0, 0), d.Black, ZP, f, "AB")
+	d.Flush()
+	const black = 0x000000FF
+	if n := memcount(d, Rect(0, 6, 10, 16), black); n != 100 {
+		t.Errorf("A has %d black pixels, want 100", n)
+	}
+	if n := memcount(d, Rect(12+2, 11, 12+7, 16), black); n != 25 {
+		t.Errorf("B has %d black pixels, want 25", n)
+	}
+
+	if err := f.AddFallback(f); err == nil {
+		t.Error("AddFallback of the font itself succeeded")
+	}
+	if err := f.AddFallback(nil); err == nil {
+		t.Error("AddFallback of nil succeeded")
+	}
+	d2 := memDisplay(t, RGB24)
+	if err := f.AddFallback(d2.DefaultFont); err == nil {
+		t.Error("AddFallback of a font on another display succeeded")
+	}
+}
+
+func TestFontFallbackAscent(t *testing.T) {
+	d := memDisplay(t, RGB24)
+	glyphs := testttfglyphs()
+	f, err := d.BuildTTF(buildttf(glyphs, map[rune]int{'A': 1}), "small.ttf", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	big := buildttf(glyphs, map[rune]int{'B': 1})
+	fb, err := d.BuildTTF(big, "big.ttf", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddFallback(fb); err != nil {
+		t.Fatal(err)
+	}
+
+	// fb's 10×10 square sits on f's baseline, 8 pixels down, so its
+	// top 2 rows are cut off.
+	const black = 0x000000FF
+	d.Image.String(Pt(0, 0), d.Black, ZP, f, "B")
+	d.Flush()
+	if n := memcount(d, Rect(0, 0, 12, 10), black); n != 80 {
+		t.Errorf("B through the fallback has %d black pixels, want 80", n)
+	}
+
+	// The fallback, and a new font sharing its subfont, are unchanged.
+	fresh, err := d.BuildTTF(big, "big.ttf", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, g := range []*Font{fb, fresh} {
+		y := 20 + 20*i
+		d.Image.String(Pt(0, y), d.Black, ZP, g, "B")
+		d.Flush()
+		if n := memcount(d, Rect(0, y, 12, y+20), black); n != 100 {
+			t.Errorf("font %d: B has %d black pixels, want 100", i, n)
+		}
+		if n := memcount(d, Rect(0, y+6, 10, y+16), black); n != 100 {
+			t.Errorf("font %d: B misplaced", i)
+		}
+	}
+}
+
+func TestParseCmapBounds(t *testing.T) {
+	be := binary.BigEndian
+	// cmap12 returns a cmap with a format 12 subtable of the given
+	// groups, each (first rune, last rune, first glyph).
+	cmap12 := func(groups [][3]int) []byte {
+		b := make([]byte, 12+16+12*len(groups))
+		be.PutUint16(b[2:], 1)
+		be.PutUint16(b[4:], 3)
+		be.PutUint16(b[6:], 10)
+		be.PutUint32(b[8:], 12)
+		s := b[12:]
+		be.PutUint16(s, 12)
+		be.PutUint32(s[4:], uint32(len(s)))
+		be.PutUint32(s[12:], uint32(len(groups)))
+		for i, g := range groups {
+			for k, v := range g {
+				be.PutUint32(s[16+12*i+4*k:], uint32(v))
+			}
+		}
+		return b
+	}
+
+	// A group over all of Unicode maps only as far as the glyphs go.
+	m, err := parsecmap(cmap12([][3]int{{0, 0x10FFFF, 1}, {'a', 0x10FFFF, 9}}), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 3 || m[1] != 2 || m[3] != 4 {
+		t.Errorf("oversized group mapped %v", m)
+	}
+
+	// Many overlapping groups cannot make parsecmap spin.
+	many := make([][3]int, 100000)
+	for i := range many {
+		many[i] = [3]int{0, 0x10FFFF, 0}
+	}
+	start := time.Now()
+	if _, err := parsecmap(cmap12(many), 0xFFFF); err == nil {
+		t.Error("overlapping format 12 groups accepted")
+	}
+
+	// Nor can overlapping format 4 segments.
+	const nseg = 1000
+	b := make([]byte, 12+16+8*nseg)
+	be.PutUint16(b[2:], 1)
+	be.PutUint16(b[4:], 3)
+	be.PutUint16(b[6:], 1)
+	be.PutUint32(b[8:], 12)
+	s := b[12:]
+	be.PutUint16(s, 4)
+	be.PutUint16(s[6:], 2*nseg)
+	for i := 0; i < nseg; i++ {
+		be.PutUint16(s[14+2*i:], 0xFFFE)
+	}
+	if _, err := parsecmap(b, 0xFFFF); err == nil {
+		t.Error("overlapping format 4 segments accepted")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("parsing hostile tables took %v", d)
+	}
+}
@@ -0,0 +1,119 @@
+package draw
+
+import "math"
+
+// ttraster accumulates the signed area covered by a glyph outline in
+// each pixel of a w by h bitmap, so that antialiased coverage can be
+// found with one pass over the result. Lines must be added with x in
+// [0, w] and are clipped to y in [0, h].
+//
+// It is the accumulation rasterizer of font-rs: each edge adds the
+// area to its right within each pixel row it crosses, and a running
+// sum along the rows then gives the winding coverage of every pixel.
+type ttraster struct {
+	w, h int
+	acc  []float64
+}
+
+func newttraster(w, h int) *ttraster {
+	return &ttraster{w: w, h: h, acc: make([]float64, w*h+w+4)}
+}
+
+// line adds the edge from (x0, y0) to (x1, y1), with y down.
+func (r *ttraster) line(x0, y0, x1, y1 float64) {
+	if y0 == y1 {
+		return
+	}
+	dir := 1.0
+	if y0 > y1 {
+		dir = -1
+		x0, y0, x1, y1 = x1, y1, x0, y0
+	}
+	dxdy := (x1 - x0) / (y1 - y0)
+	x := x0
+	if y0 < 0 {
+		x -= y0 * dxdy
+	}
+	end := int(math.Ceil(y1))
+	if end > r.h {
+		end = r.h
+	}
+	for y := int(math.Max(0, y0)); y < end; y++ {
+		row := r.acc[y*r.w:]
+		dy := math.Min(float64(y+1), y1) - math.Max(float64(y), y0)
+		xnext := x + dxdy*dy
+		d := dy * dir
+		// Rounding may take x a little outside the bitmap.
+		xa := math.Max(0, math.Min(x, float64(r.w)))
+		xb := math.Max(0, math.Min(xnext, float64(r.w)))
+		if xa > xb {
+			xa, xb = xb, xa
+		}
+		xaf := math.Floor(xa)
+		xai := int(xaf)
+		xbc := math.Ceil(xb)
+		xbi := int(xbc)
+		if xbi <= xai+1 {
+			// Within one pixel: split by the mean x.
+			xm := 0.5*(xa+xb) - xaf
+			row[xai] += d - d*xm
+			row[xai+1] += d * xm
+		} else {
+			s := 1 / (xb - xa)
+			xa0 := xa - xaf
+			a0 := 0.5 * s * (1 - xa0) * (1 - xa0)
+			xb1 := xb - xbc + 1
+			am := 0.5 * s * xb1 * xb1
+			row[xai] += d * a0
+			if xbi == xai+2 {
+				row[xai+1] += d * (1 - a0 - am)
+			} else {
+				a1 := s * (1.5 - xa0)
+				row[xai+1] += d * (a1 - a0)
+				for xi := xai + 2; xi < xbi-1; xi++ {
+					row[xi] += d * s
+				}
+				a2 := a1 + float64(xbi-xai-3)*s
+				row[xbi-1] += d * (1 - a2 - am)
+			}
+			row[xbi] += d * am
+		}
+		x = xnext
+	}
+}
+
+// quad adds the quadratic Bézier curve from p0 to p2 with control
+// point p1, as enough lines that it looks smooth.
+func (r *ttraster) quad(x0, y0, x1, y1, x2, y2 float64) {
+	ddx := x0 - 2*x1 + x2
+	ddy := y0 - 2*y1 + y2
+	n := 1 + int(math.Sqrt(math.Sqrt(ddx*ddx+ddy*ddy)*4))
+	if n > 64 {
+		n = 64
+	}
+	px, py := x0, y0
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		u := 1 - t
+		x := u*u*x0 + 2*u*t*x1 + t*t*x2
+		y := u*u*y0 + 2*u*t*y1 + t*t*y2
+		r.line(px, py, x, y)
+		px, py = x, y
+	}
+}
+
+// coverage returns the coverage of each pixel as a grey value, one
+// byte per pixel.
+func (r *ttraster) coverage() []byte {
+	b := make([]byte, r.w*r.h)
+	sum := 0.0
+	for i := range b {
+		sum += r.acc[i]
+		a := math.Abs(sum)
+		if a > 1 {
+			a = 1
+		}
+		b[i] = uint8(a*255 + 0.5)
+	}
+	return b
+}
@@ -1,10 +1,5 @@
 package draw
 
-import (
-	"fmt"
-	"io"
-)
-
 // atoi parses a decimal integer from a string, ignoring whitespace.
 func atoi(s string) int {
 	s = trimSpace(s)
@@ -58,45 +53,3 @@ func wordsPerLine(r Rectangle, d int) int {
 func bytesPerLine(r Rectangle, d int) int {
 	return unitsPerLine(r, d, 8)
 }
-
-// ReadImageReader reads an image from an io.Reader (not just *os.File).
-func (d *Display) ReadImageReader(r io.Reader) (*Image, error) {
-	// Read image header: 5 × 12 bytes
-	header := make([]byte, 5*12)
-	if _, err := io.ReadFull(r, header); err != nil {
-		return nil, fmt.Errorf("readimage: header read error: %v", err)
-	}
-
-	chanstr := string(header[0:11])
-	pix := strtochan(chanstr)
-	if pix == 0 {
-		return nil, fmt.Errorf("readimage: bad channel string: %s", chanstr)
-	}
-
-	minx := atoi(string(header[12:23]))
-	miny := atoi(string(header[24:35]))
-	maxx := atoi(string(header[36:47]))
-	maxy := atoi(string(header[48:59]))
-
-	rect := Rect(minx, miny, maxx, maxy)
-
-	img, err := d.AllocImage(rect, pix, false, DTransparent)
-	if err != nil {
-		return nil, err
-	}
-
-	depth := chantodepth(pix)
-	bpl := bytesPerLine(rect, depth)
-	data := make([]byte, bpl*rect.Dy())
-	if _, err := io.ReadFull(r, data); err != nil {
-		img.Free()
-		return nil, fmt.Errorf("readimage: data read error: %v", err)
-	}
-
-	if _, err := img.Load(rect, data); err != nil {
-		img.Free()
-		return nil, err
-	}
-
-	return img, nil
-}
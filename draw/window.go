@@ -201,7 +201,9 @@ func (d *Display) gengetwindow(winnamepath string, ref int) error {
 	// Free old screen/window if reattaching
 	if d.screen != nil {
 		if d.ScreenImage != nil {
+			d.mu.Lock()
 			d.ScreenImage.freeimage1()
+			d.mu.Unlock()
 		}
 		if d.screen.Image != nil && d.screen.Image != d.Image {
 			d.screen.Image.Free()
@@ -296,6 +298,9 @@ func (d *Display) Namedimage(name string) (*Image, error) {
 	if n >= 256 {
 		return nil, fmt.Errorf("name too long")
 	}
+	if d.ctlfd == nil {
+		return nil, fmt.Errorf("namedimage: display has no ctl file")
+	}
 
 	// Flush pending data so we don't get error allocating the image
 	if err := d.doflush(); err != nil {
@@ -366,6 +371,7 @@ func (d *Display) Namedimage(name string) (*Image, error) {
 	img.Clipr.Min.Y = atoi(fields[9])
 	img.Clipr.Max.X = atoi(fields[10])
 	img.Clipr.Max.Y = atoi(fields[11])
+	d.noteAlloc(img)
 
 	return img, nil
 }
@@ -1,7 +1,6 @@
 package draw
 
 import (
-	"compress/zlib"
 	"fmt"
 	"io"
 	"os"
@@ -20,7 +19,11 @@ const (
 // Port of 9front _compblocksize().
 func CompBlockSize(r Rectangle, depth int) int {
 	bpl := bytesPerLine(r, depth)
-	return bpl * r.Dy()
+	bpl = 2 * bpl // add plenty extra for blocking, etc.
+	if bpl < NCBLOCK {
+		return NCBLOCK
+	}
+	return bpl
 }
 
 // WriteImage writes an uncompressed image to a writer.
@@ -45,10 +48,7 @@ func (i *Image) WriteImageWriter(w io.Writer) error {
 
 	// We can only unload from display-backed images
 	if i.Display != nil {
-		depth := i.Depth
-		bpl := bytesPerLine(i.R, depth)
-		data := make([]byte, bpl*i.R.Dy())
-		_, err := i.Unload(i.R, data)
+		data, err := i.ReadPixels(i.R)
 		if err != nil {
 			return err
 		}
@@ -71,8 +71,7 @@ func (i *Image) WriteImageFile(name string) error {
 
 // CwriteImage writes a compressed image.
 // The 9front format uses "compressed\n" marker followed by the header,
-// then compressed blocks with per-block headers.
-// We use Go's zlib for the compression.
+// then compressed blocks with per-block headers (see image(6)).
 func (i *Image) CwriteImage(f *os.File) error {
 	return i.CwriteImageWriter(f)
 }
@@ -100,20 +99,122 @@ func (i *Image) CwriteImageWriter(w io.Writer) error {
 		return nil
 	}
 
-	// Unload and compress image data
+	// Read and compress image data
 	depth := i.Depth
 	bpl := bytesPerLine(i.R, depth)
-	data := make([]byte, bpl*i.R.Dy())
-	if _, err := i.Unload(i.R, data); err != nil {
+	data, err := i.ReadPixels(i.R)
+	if err != nil {
 		return err
 	}
 
-	zw := zlib.NewWriter(w)
-	if _, err := zw.Write(data); err != nil {
-		zw.Close()
+	return compressLines(data, bpl, i.R.Min.Y, CompBlockSize(i.R, depth), func(maxy int, block []byte) error {
+		if _, err := fmt.Fprintf(w, "%11d %11d ", maxy, len(block)); err != nil {
+			return err
+		}
+		_, err := w.Write(block)
 		return err
+	})
+}
+
+// Parameters of the hash chains used to find matches.
+const (
+	hshift = 3
+	nhash  = 1 << (hshift * NMATCH)
+	hmask  = nhash - 1
+)
+
+func hupdate(h int, c byte) int {
+	return (h<<hshift ^ int(c)) & hmask
+}
+
+// compressLines compresses image data of bpl bytes per line, the first
+// line being miny, into blocks of whole lines no larger than ncblock
+// bytes, and passes each to emit with the y just below its last line.
+//
+// Within a block each code byte c is either a literal run, c >= 128,
+// of the c-127 bytes that follow, or a back reference to (c>>2)+NMATCH
+// bytes of earlier output in the same block, at an offset of one plus
+// the 10 bits made of the low two bits of c and the next byte. Neither
+// crosses the end of a line.
+// Port of the encoder in 9front writeimage().
+func compressLines(data []byte, bpl, miny, ncblock int, emit func(maxy int, block []byte) error) error {
+	var head [nhash]int
+	prev := make([]int, len(data)) // earlier position with the same hash, or -1
+	out := make([]byte, 0, ncblock)
+	var dump []byte
+	flush := func() {
+		if len(dump) > 0 {
+			out = append(out, byte(len(dump)-1+128))
+			out = append(out, dump...)
+			dump = dump[:0]
+		}
+	}
+
+	line := 0
+	for line < len(data) {
+		for k := range head {
+			head[k] = -1
+		}
+		out = out[:0]
+		nline := 0
+		for ; line < len(data); line += bpl {
+			lout := len(out)
+			eline := line + bpl
+			for p := line; p < eline; {
+				es := min(eline, p+NRUN)
+				runlen, q := 0, 0
+				var h int
+				if p+NMATCH <= len(data) {
+					h = hupdate(hupdate(hupdate(0, data[p]), data[p+1]), data[p+2])
+					for c := head[h]; c >= 0 && p-c <= NMEM; c = prev[c] {
+						n := 0
+						for p+n < es && data[c+n] == data[p+n] {
+							n++
+						}
+						if n > runlen {
+							runlen, q = n, c
+							if n == es-p {
+								break
+							}
+						}
+					}
+				}
+				if runlen < NMATCH {
+					if len(dump) == NDUMP {
+						flush()
+					}
+					dump = append(dump, data[p])
+					runlen = 1
+				} else {
+					flush()
+					offs := p - q - 1
+					out = append(out, byte((runlen-NMATCH)<<2+offs>>8), byte(offs))
+				}
+				for end := p + runlen; p < end; p++ {
+					if p+NMATCH <= len(data) {
+						h = hupdate(hupdate(hupdate(0, data[p]), data[p+1]), data[p+2])
+						prev[p] = head[h]
+						head[h] = p
+					}
+				}
+			}
+			flush()
+			if len(out) > ncblock {
+				// The line does not fit; it starts the next block.
+				out = out[:lout]
+				break
+			}
+			nline++
+		}
+		if nline == 0 {
+			return fmt.Errorf("cwriteimage: line too long for block")
+		}
+		if err := emit(miny+nline, out); err != nil {
+			return err
+		}
+		miny += nline
 	}
-	return zw.Close()
+	return nil
 }
 
 // WriteImageHeader writes just the image header to a writer.
@@ -2,6 +2,7 @@ package draw
 
 import (
 	"bytes"
+	"io"
 	"strings"
 	"testing"
 )
@@ -30,10 +31,13 @@ func TestCompBlockSize(t *testing.T) {
 	r := Rect(0, 0, 100, 50)
 	depth := 8
 	bs := CompBlockSize(r, depth)
-	bpl := bytesPerLine(r, depth)
-	expected := bpl * 50
-	if bs != expected {
-		t.Errorf("CompBlockSize = %d, want %d", bs, expected)
+	if bs != NCBLOCK {
+		t.Errorf("CompBlockSize = %d, want %d", bs, NCBLOCK)
+	}
+	// Wide images get twice a line.
+	r = Rect(0, 0, 4000, 1)
+	if bs := CompBlockSize(r, depth); bs != 8000 {
+		t.Errorf("CompBlockSize = %d, want 8000", bs)
 	}
 }
 
@@ -166,3 +170,35 @@ func TestCwriteImageWriterNoDisplay(t *testing.T) {
 		t.Error("missing compressed marker")
 	}
 }
+
+func TestWriteImageWide(t *testing.T) {
+	// Lines wider than a single read message.
+	d := memDisplay(t, RGB24)
+	r := Rect(0, 0, 2000, 3)
+	i, data := loadTestImage(t, d, r, RGBA32)
+	for _, tt := range []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{"WriteImageWriter", i.WriteImageWriter},
+		{"CwriteImageWriter", i.CwriteImageWriter},
+	} {
+		var buf bytes.Buffer
+		if err := tt.write(&buf); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		j, err := d.ReadImageReader(&buf)
+		if err != nil {
+			t.Errorf("%s: read back: %v", tt.name, err)
+			continue
+		}
+		got, err := j.ReadPixels(j.R)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if j.R != r || !bytes.Equal(got, data) {
+			t.Errorf("%s: image differs after round trip", tt.name)
+		}
+	}
+}
@@ -32,6 +32,10 @@ const (
 // FRTICKW is the width of the typing cursor tick in pixels.
 const FRTICKW = 3
 
+// DefTabstop is the default tab stop interval, in widths of the
+// digit zero.
+const DefTabstop = 8
+
 // frbox is an internal box within a frame.
 //
 // If nrune >= 0 the box holds nrune runes of text stored in ptr
@@ -2,6 +2,8 @@ package frame
 
 import (
 	"testing"
+
+	"github.com/elizafairlady/go-libui/draw"
 )
 
 // Tests for frame internals that don't require a display connection.
@@ -188,3 +190,77 @@ func TestConstants(t *testing.T) {
 		t.Error("color constants are wrong")
 	}
 }
+
+func TestRunes(t *testing.T) {
+	f := &Frame{}
+	f.growbox(5)
+	f.nbox = 4
+	f.box[0] = frbox{nrune: 3, ptr: []byte("a日c")}
+	f.box[1] = frbox{nrune: -1, bc: '\t'}
+	f.box[2] = frbox{nrune: 2, ptr: []byte("de")}
+	f.box[3] = frbox{nrune: -1, bc: '\n'}
+
+	if got := string(f.runes()); got != "a日c\tde\n" {
+		t.Errorf("runes = %q, want %q", got, "a日c\tde\n")
+	}
+}
+
+func TestSetTabstop(t *testing.T) {
+	// Without a cache the font reports Height/2 per character.
+	f := &Frame{Font: &draw.Font{Height: 16}}
+	f.SetTabstop(4)
+	if f.Maxtab != 32 {
+		t.Errorf("Maxtab = %d, want 32", f.Maxtab)
+	}
+	f.SetTabstop(0)
+	if f.Maxtab != DefTabstop*8 {
+		t.Errorf("Maxtab after reset = %d, want %d", f.Maxtab, DefTabstop*8)
+	}
+}
+
+func TestStringWidth(t *testing.T) {
+	// Characters are 8 wide, as in TestSetTabstop.
+	f := &Frame{
+		Font:   &draw.Font{Height: 16},
+		R:      draw.Rect(10, 0, 400, 100),
+		Maxtab: 32,
+	}
+	tests := []struct {
+		x    int
+		s    string
+		want int
+	}{
+		{10, "ab", 16},
+		{10, "ab\tc", 32 + 8}, // the tab reaches the stop at 42
+		{36, "\tc", 8 + 8},    // the stop at 42 is too close: a space
+		{10, "\t\t", 64},      // two whole stops
+		{10, "a\nbcdef", 8},   // stops at the newline
+		{10, "", 0},
+	}
+	for _, tt := range tests {
+		if got := f.StringWidth(tt.x, tt.s); got != tt.want {
+			t.Errorf("StringWidth(%d, %q) = %d, want %d", tt.x, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestNewwidTab(t *testing.T) {
+	f := &Frame{
+		R:      draw.Rect(10, 0, 200, 100),
+		Maxtab: 32,
+	}
+	tab := frbox{nrune: -1, bc: '\t', minwid: 4}
+	tests := []struct {
+		x, want int
+	}{
+		{10, 32}, // at the margin: a full stop
+		{20, 22}, // snaps to the stop at 42
+		{40, 4},  // too close to the stop at 42: minimum width
+		{190, 4}, // past the last stop: minimum width
+	}
+	for _, tt := range tests {
+		if got := f.newwid0(draw.Pt(tt.x, 0), &tab); got != tt.want {
+			t.Errorf("newwid0 at x=%d = %d, want %d", tt.x, got, tt.want)
+		}
+	}
+}
@@ -11,7 +11,7 @@ import (
 func (f *Frame) Init(r draw.Rectangle, ft *draw.Font, b *draw.Image, cols [NCol]*draw.Image) {
 	f.Font = ft
 	f.Display = b.Display
-	f.Maxtab = 8 * ft.StringWidth("0")
+	f.Maxtab = DefTabstop * ft.StringWidth("0")
 	f.nbox = 0
 	f.nalloc = 0
 	f.Nchars = 0
@@ -89,6 +89,58 @@ func (f *Frame) SetRects(r draw.Rectangle, b *draw.Image) {
 	f.Maxlines = (r.Max.Y - r.Min.Y) / f.Font.Height
 }
 
+// SetTabstop sets the tab stop interval to n widths of the digit
+// zero in the frame's font; n <= 0 restores DefTabstop. Text already
+// in the frame is laid out again with the new stops and the selection
+// is preserved. Text pushed off the bottom of the frame is dropped,
+// as with Insert.
+func (f *Frame) SetTabstop(n int) {
+	if n <= 0 {
+		n = DefTabstop
+	}
+	maxtab := n * f.Font.StringWidth("0")
+	if maxtab <= 0 {
+		maxtab = n
+	}
+	if maxtab == f.Maxtab {
+		return
+	}
+	if f.Nchars == 0 || f.B == nil {
+		f.Maxtab = maxtab
+		return
+	}
+
+	p0, p1 := f.P0, f.P1
+	text := f.runes()
+	f.Delete(0, f.Nchars)
+	f.Maxtab = maxtab
+	f.Insert(text, 0)
+
+	if p1 > f.Nchars {
+		p1 = f.Nchars
+	}
+	if p0 > p1 {
+		p0 = p1
+	}
+	f.DrawSel(f.PtOfChar(f.P0), f.P0, f.P1, false)
+	f.P0, f.P1 = p0, p1
+	f.DrawSel(f.PtOfChar(p0), p0, p1, true)
+}
+
+// runes returns the text held in the frame's boxes.
+func (f *Frame) runes() []rune {
+	text := make([]rune, 0, f.Nchars)
+	for nb := 0; nb < f.nbox; nb++ {
+		b := &f.box[nb]
+		if b.nrune < 0 {
+			text = append(text, b.bc)
+		} else {
+			text = append(text, []rune(string(b.ptr))...)
+		}
+	}
+	return text
+}
+
 // Clear frees the internal box structures. If freeall is true,
 // also frees the tick images.
 func (f *Frame) Clear(freeall bool) {
@@ -110,3 +162,25 @@ func (f *Frame) Clear(freeall bool) {
 	}
 	f.Ticked = 0
 }
+
+// StringWidth returns the width of s in the frame when it starts at
+// x, measured as the frame lays text out: tabs reach the next tab
+// stop, or take the width of a space if the stop is closer than that.
+// It agrees with PtOfChar and CharOfPt where Font.StringWidth, which
+// gives a tab the width of its glyph, does not. Measurement stops at a
+// newline, and lines are not wrapped.
+func (f *Frame) StringWidth(x int, s string) int {
+	tab := frbox{nrune: -1, bc: '\t', minwid: f.Font.StringWidth(" ")}
+	pt := draw.Pt(x, 0)
+	for _, c := range s {
+		switch c {
+		case '\n':
+			return pt.X - x
+		case '\t':
+			pt.X += f.newwid0(pt, &tab)
+		default:
+			pt.X += f.Font.RuneWidth(c)
+		}
+	}
+	return pt.X - x
+}
@@ -0,0 +1,259 @@
+// Package plumb is a client for the Plan 9 plumber, plumber(4).
+//
+// Programs send messages to the plumber's send port, and receive the
+// messages routed to them by reading the port named after them, such
+// as edit or web. A message carries some text, its source and
+// destination, the directory it is relative to, and optional name=value
+// attributes.
+//
+// Ported from 9front /sys/src/libplumb.
+package plumb
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Dir is where the plumber is mounted.
+var Dir = "/mnt/plumb"
+
+// MaxData is the most data ReadMsg accepts in a message, so that a
+// corrupt length cannot make it allocate without bound.
+const MaxData = 1 << 20
+
+// Attr is a message attribute.
+type Attr struct {
+	Name  string
+	Value string
+}
+
+// Msg is a plumb message.
+type Msg struct {
+	Src  string // the program that sent it
+	Dst  string // the port it is for; empty lets the rules decide
+	Wdir string // the directory Data is relative to
+	Type string // usually text
+	Attr []Attr
+	Data []byte
+}
+
+// LookupAttr returns the value of the named attribute and whether it
+// is present.
+// Port of 9front plumblookup().
+func (m *Msg) LookupAttr(name string) (string, bool) {
+	for _, a := range m.Attr {
+		if a.Name == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// Pack returns m in the form written to and read from plumb ports:
+// a line each for the source, destination, directory, type, attributes
+// and length of the data, followed by the data.
+// Port of 9front plumbpack().
+func (m *Msg) Pack() []byte {
+	var b bytes.Buffer
+	for _, s := range []string{m.Src, m.Dst, m.Wdir, m.Type, PackAttr(m.Attr)} {
+		b.WriteString(s)
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "%d\n", len(m.Data))
+	b.Write(m.Data)
+	return b.Bytes()
+}
+
+// PackAttr returns attrs as a space-separated list of name=value
+// pairs, quoting values that need it.
+// Port of 9front plumbpackattr().
+func PackAttr(attrs []Attr) string {
+	var b strings.Builder
+	for i, a := range attrs {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(a.Name)
+		b.WriteByte('=')
+		b.WriteString(quote(a.Value))
+	}
+	return b.String()
+}
+
+// quote returns s in single quotes, with quotes doubled, if it is
+// empty or holds white space or quotes.
+func quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// UnpackAttr parses an attribute list made by PackAttr.
+// Port of 9front plumbunpackattr().
+func UnpackAttr(s string) []Attr {
+	var attrs []Attr
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return attrs
+		}
+		i := strings.IndexAny(s, "= \t")
+		if i < 0 || s[i] != '=' {
+			// A name without a value; skip it.
+			if i < 0 {
+				return attrs
+			}
+			s = s[i:]
+			continue
+		}
+		a := Attr{Name: s[:i]}
+		s = s[i+1:]
+		if strings.HasPrefix(s, "'") {
+			var v strings.Builder
+			s = s[1:]
+			for s != "" {
+				if s[0] == '\'' {
+					if len(s) < 2 || s[1] != '\'' {
+						s = s[1:]
+						break
+					}
+					s = s[1:]
+				}
+				v.WriteByte(s[0])
+				s = s[1:]
+			}
+			a.Value = v.String()
+		} else {
+			j := strings.IndexAny(s, " \t")
+			if j < 0 {
+				j = len(s)
+			}
+			a.Value, s = s[:j], s[j:]
+		}
+		attrs = append(attrs, a)
+	}
+}
+
+// Unpack parses a message made by Pack.
+// Port of 9front plumbunpack().
+func Unpack(b []byte) (*Msg, error) {
+	return ReadMsg(bufio.NewReader(bytes.NewReader(b)))
+}
+
+// ReadMsg reads one message from r. A message may arrive in several
+// reads, so r should be kept for the next message. Messages with more
+// than MaxData bytes of data are rejected.
+func ReadMsg(r *bufio.Reader) (*Msg, error) {
+	var f [6]string
+	for i := range f {
+		s, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && i == 0 && s == "" {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("plumb: short message")
+		}
+		f[i] = s[:len(s)-1]
+	}
+	n, err := strconv.Atoi(f[5])
+	if err != nil || n < 0 || n > MaxData {
+		return nil, fmt.Errorf("plumb: bad data length %q", f[5])
+	}
+	m := &Msg{
+		Src:  f[0],
+		Dst:  f[1],
+		Wdir: f[2],
+		Type: f[3],
+		Attr: UnpackAttr(f[4]),
+		Data: make([]byte, n),
+	}
+	if _, err := io.ReadFull(r, m.Data); err != nil {
+		return nil, fmt.Errorf("plumb: short message")
+	}
+	return m, nil
+}
+
+// A Port is an open plumb port.
+type Port struct {
+	rw   io.ReadWriteCloser
+	r    *bufio.Reader
+	done chan struct{} // closed by Close
+	once sync.Once     // guards done
+}
+
+// Open opens the named port of the plumber, such as "send" or "edit",
+// with the given flag, as for os.OpenFile. A name starting with / is
+// taken as the path of the port file.
+// Port of 9front plumbopen().
+func Open(name string, flag int) (*Port, error) {
+	if !strings.HasPrefix(name, "/") {
+		name = Dir + "/" + name
+	}
+	f, err := os.OpenFile(name, flag, 0)
+	if err != nil {
+		return nil, fmt.Errorf("plumb: %v", err)
+	}
+	return NewPort(f), nil
+}
+
+// NewPort returns a Port that reads and writes messages on rw, for
+// plumbers reached some other way than through the file system.
+func NewPort(rw io.ReadWriteCloser) *Port {
+	return &Port{rw: rw, r: bufio.NewReader(rw), done: make(chan struct{})}
+}
+
+// Send sends m on the port in a single write.
+// Port of 9front plumbsend().
+func (p *Port) Send(m *Msg) error {
+	_, err := p.rw.Write(m.Pack())
+	return err
+}
+
+// SendText sends the text data, from the program src to the port dst,
+// relative to the directory wdir.
+// Port of 9front plumbsendtext().
+func (p *Port) SendText(src, dst, wdir, data string) error {
+	return p.Send(&Msg{Src: src, Dst: dst, Wdir: wdir, Type: "text", Data: []byte(data)})
+}
+
+// Recv reads the next message, blocking until one arrives.
+// Port of 9front plumbrecv().
+func (p *Port) Recv() (*Msg, error) {
+	return ReadMsg(p.r)
+}
+
+// Listen starts receiving messages in a goroutine and returns a
+// channel on which they are delivered. The channel is closed when the
+// port is closed or a read fails, even if nobody is receiving. Listen should be called at most once,
+// and Recv not used after it.
+func (p *Port) Listen() <-chan *Msg {
+	c := make(chan *Msg)
+	go func() {
+		defer close(c)
+		for {
+			m, err := p.Recv()
+			if err != nil {
+				return
+			}
+			select {
+			case c <- m:
+			case <-p.done:
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// Close closes the port, stopping any Listen.
+func (p *Port) Close() error {
+	p.once.Do(func() { close(p.done) })
+	return p.rw.Close()
+}
@@ -0,0 +1,149 @@
+package plumb
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestAttr(t *testing.T) {
+	attrs := []Attr{
+		{"addr", "42"},
+		{"action", "showfile"},
+		{"msg", "it's a test"},
+		{"empty", ""},
+		{"tab", "a\tb"},
+	}
+	s := PackAttr(attrs)
+	if want := "addr=42 action=showfile msg='it''s a test' empty='' tab='a\tb'"; s != want {
+		t.Errorf("PackAttr = %q, want %q", s, want)
+	}
+	if got := UnpackAttr(s); !reflect.DeepEqual(got, attrs) {
+		t.Errorf("UnpackAttr = %q, want %q", got, attrs)
+	}
+	if got := UnpackAttr("  a=1  junk b='x y'"); !reflect.DeepEqual(got, []Attr{{"a", "1"}, {"b", "x y"}}) {
+		t.Errorf("UnpackAttr skipping junk = %q", got)
+	}
+}
+
+func TestPack(t *testing.T) {
+	m := &Msg{
+		Src:  "acme",
+		Dst:  "edit",
+		Wdir: "/usr/glenda",
+		Type: "text",
+		Attr: []Attr{{"addr", "/main/"}},
+		Data: []byte("lib/profile\nwith a newline"),
+	}
+	b := m.Pack()
+	want := "acme\nedit\n/usr/glenda\ntext\naddr=/main/\n26\nlib/profile\nwith a newline"
+	if string(b) != want {
+		t.Errorf("Pack = %q, want %q", b, want)
+	}
+	got, err := Unpack(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("Unpack = %+v, want %+v", got, m)
+	}
+	if v, ok := got.LookupAttr("addr"); !ok || v != "/main/" {
+		t.Errorf("LookupAttr(addr) = %q, %v", v, ok)
+	}
+	if _, ok := got.LookupAttr("none"); ok {
+		t.Error("LookupAttr found a missing attribute")
+	}
+
+	for _, bad := range []string{"a\nb\n", "a\nb\nc\nd\n\nx\n", "a\nb\nc\nd\n\n5\nabc", "a\nb\nc\nd\n\n99999999999\nabc"} {
+		if _, err := Unpack([]byte(bad)); err == nil {
+			t.Errorf("Unpack(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestPort(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := NewPort(w)
+	recv := NewPort(r)
+	c := recv.Listen()
+
+	go func() {
+		send.SendText("test", "edit", "/tmp", "file.go:12")
+		// A message arriving in pieces.
+		b := (&Msg{Src: "test", Type: "text", Data: []byte("second")}).Pack()
+		w.Write(b[:7])
+		time.Sleep(10 * time.Millisecond)
+		w.Write(b[7:])
+		send.Close()
+	}()
+	var got []string
+	for m := range c {
+		got = append(got, string(m.Data))
+	}
+	if !reflect.DeepEqual(got, []string{"file.go:12", "second"}) {
+		t.Errorf("received %q", got)
+	}
+	recv.Close()
+}
+
+func TestListenClose(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	before := runtime.NumGoroutine()
+	p := NewPort(r)
+	p.Listen()
+	// A message nobody receives must not keep the goroutine alive
+	// after Close.
+	NewPort(w).SendText("test", "", "/", "unwanted")
+	time.Sleep(10 * time.Millisecond)
+	p.Close()
+	for start := time.Now(); runtime.NumGoroutine() > before; {
+		if time.Since(start) > time.Second {
+			t.Fatal("Listen goroutine still running after Close")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	old := Dir
+	defer func() { Dir = old }()
+	Dir = t.TempDir()
+	if _, err := Open("send", os.O_WRONLY); err == nil {
+		t.Error("Open of a missing port succeeded")
+	}
+	path := filepath.Join(Dir, "send")
+	os.WriteFile(path, nil, 0666)
+	p, err := Open("send", os.O_WRONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SendText("test", "", "/", "hello")
+	p.Close()
+	b, _ := os.ReadFile(path)
+	if !bytes.Equal(b, []byte("test\n\n/\ntext\n\n5\nhello")) {
+		t.Errorf("port file = %q", b)
+	}
+	p, err = Open(path, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if m, err := p.Recv(); err != nil || string(m.Data) != "hello" {
+		t.Errorf("Recv = %+v, %v", m, err)
+	}
+	if _, err := p.Recv(); err != io.EOF {
+		t.Errorf("Recv at end = %v, want EOF", err)
+	}
+}